@@ -4,6 +4,7 @@ import { resolve, isAbsolute, dirname } from 'path';
 import { existsSync } from 'fs';
 import { createHash } from 'crypto';
 import { App, AppRenderResult } from './type';
+import { recordCacheHit, recordCacheMiss } from './cache-stats';
 import { execSync } from 'child_process';
 
 const RENDER_TIMEOUT_MS = 30000; // Increased for animated apps
@@ -176,6 +177,7 @@ export async function renderApp(options: RenderAppOptions): Promise<AppRenderRes
   const cachedPng = resolve(cacheDir, `${cacheKey}.png`);
 
   if (existsSync(cachedApng)) {
+    recordCacheHit('apps');
     console.log(
       `Using cached animated app "${app.id}" (hash: ${cacheKey}) from ${cachedApng}`,
     );
@@ -188,6 +190,7 @@ export async function renderApp(options: RenderAppOptions): Promise<AppRenderRes
   }
 
   if (existsSync(cachedPng)) {
+    recordCacheHit('apps');
     console.log(
       `Using cached static app "${app.id}" (hash: ${cacheKey}) from ${cachedPng}`,
     );
@@ -197,6 +200,7 @@ export async function renderApp(options: RenderAppOptions): Promise<AppRenderRes
       path: cachedPng,
     };
   }
+  recordCacheMiss('apps');
 
   // Resolve index.html
   const appDir = isAbsolute(app.src)
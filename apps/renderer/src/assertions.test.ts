@@ -0,0 +1,67 @@
+import { describe, it, expect } from 'vitest';
+import { HTMLParser } from './html-parser';
+import { HTMLProjectParser } from './html-project-parser';
+import { runAssertions } from './assertions';
+
+describe('runAssertions', () => {
+  // Text checks use the timeline only, nothing is rendered
+  const parseProject = () =>
+    new HTMLProjectParser(
+      new HTMLParser().parse(`
+        <title>Test</title>
+        <outputs>
+          <output name="youtube" resolution="1920x1080" fps="30" />
+        </outputs>
+        <project>
+          <sequence id="main">
+            <fragment id="intro" style="-duration: 2s;">
+              <container><p>Intro</p></container>
+            </fragment>
+            <marker name="hook" />
+            <fragment id="pitch" style="-duration: 2s;">
+              <container><p>Hook</p></container>
+            </fragment>
+            <marker name="cta" />
+            <fragment id="outro" style="-duration: 2s;">
+              <container><p>Outro</p></container>
+            </fragment>
+          </sequence>
+        </project>
+        <tests>
+          <assert id="intro" at="1s" contains-text="Intro" />
+          <assert id="hook" at="3s" contains-text="Hook" />
+          <assert id="typo" at="3500ms" contains-text="Hoook" />
+          <assert id="length" duration="6s" />
+        </tests>
+      `),
+      '/tmp/test/project.html',
+    ).parse();
+
+  it('should only check the assertions between two markers', async () => {
+    const project = await parseProject();
+
+    const results = await runAssertions(project, '/tmp', undefined, [
+      'hook',
+      'cta',
+    ]);
+    expect(
+      results.map(({ assertion, failures }) => [
+        assertion.name,
+        failures.length,
+      ]),
+    ).toEqual([
+      ['hook', 0],
+      ['typo', 1],
+    ]);
+
+    expect(await runAssertions(project, '/tmp')).toHaveLength(4);
+  });
+
+  it('should explain unknown markers', async () => {
+    const project = await parseProject();
+
+    await expect(
+      runAssertions(project, '/tmp', undefined, ['hook', 'end']),
+    ).rejects.toThrow('Marker "end" not found');
+  });
+});
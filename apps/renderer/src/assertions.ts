@@ -0,0 +1,228 @@
+import { readFileSync } from 'fs';
+import { resolve } from 'path';
+import { Project } from './project';
+import { Assertion, Output } from './type';
+import { makeFrameFFmpegCommand, runFFMpeg } from './ffmpeg';
+import { formatChapterTime } from './time-utils';
+
+export type AssertionResult = {
+  assertion: Assertion;
+  output: string;
+  failures: string[]; // empty if the assertion passed
+};
+
+/**
+ * Visible text of an HTML snippet: tags removed, whitespace collapsed
+ */
+export function getTextContent(html: string): string {
+  return html
+    .replace(/<(script|style)\b[^>]*>[\s\S]*?<\/\1>/gi, ' ')
+    .replace(/<[^>]*>/g, ' ')
+    .replace(/&nbsp;/g, ' ')
+    .replace(/&lt;/g, '<')
+    .replace(/&gt;/g, '>')
+    .replace(/&quot;/g, '"')
+    .replace(/&#39;/g, "'")
+    .replace(/&amp;/g, '&')
+    .replace(/\s+/g, ' ')
+    .trim();
+}
+
+function formatTime(time: number): string {
+  return `${formatChapterTime(time)} (${Math.round(time)}ms)`;
+}
+
+/**
+ * Checks the duration of the timeline (or of the assertion's sequence)
+ * Note: This must be called after build()
+ */
+export function checkDuration(
+  project: Project,
+  assertion: Assertion,
+): string | undefined {
+  if (!assertion.duration) {
+    return undefined;
+  }
+
+  const actual = project.getTimelineDuration(assertion.sequence);
+  const { value, tolerance } = assertion.duration;
+  if (Math.abs(actual - value) > tolerance) {
+    return `duration is ${Math.round(actual)}ms, expected ${value}ms (±${tolerance}ms)`;
+  }
+
+  return undefined;
+}
+
+/**
+ * Checks that a container visible at the assertion's time contains the text
+ * Note: This must be called after build()
+ */
+export function checkText(
+  project: Project,
+  assertion: Assertion,
+): string | undefined {
+  if (assertion.containsText === undefined || assertion.at === undefined) {
+    return undefined;
+  }
+
+  const fragments = project.getFragmentsAt(assertion.at, assertion.sequence);
+  const texts = fragments
+    .filter((fragment) => fragment.container)
+    .map((fragment) => getTextContent(fragment.container!.htmlContent));
+
+  if (texts.some((text) => text.includes(assertion.containsText!))) {
+    return undefined;
+  }
+
+  const visible =
+    texts.length > 0
+      ? `visible text: ${texts.map((text) => `"${text}"`).join(', ')}`
+      : `visible fragments: ${fragments.map((fragment) => fragment.id).join(', ') || 'none'}`;
+
+  return `no container at ${formatTime(assertion.at)} contains "${assertion.containsText}" (${visible})`;
+}
+
+/**
+ * Checks the color of a pixel of a raw rgb24 frame
+ */
+export function checkPixel(
+  frame: Buffer,
+  output: Output,
+  assertion: Assertion,
+): string | undefined {
+  if (!assertion.pixel) {
+    return undefined;
+  }
+
+  const { x, y, color, tolerance } = assertion.pixel;
+  const { width, height } = output.resolution;
+  if (x >= width || y >= height) {
+    return `pixel ${x},${y} is outside of the ${width}x${height} frame`;
+  }
+
+  const offset = (y * width + x) * 3;
+  if (offset + 3 > frame.length) {
+    return `frame is incomplete, pixel ${x},${y} was not rendered`;
+  }
+
+  const actual = {
+    r: frame[offset],
+    g: frame[offset + 1],
+    b: frame[offset + 2],
+  };
+  const difference = Math.max(
+    Math.abs(actual.r - color.r),
+    Math.abs(actual.g - color.g),
+    Math.abs(actual.b - color.b),
+  );
+  if (difference > tolerance) {
+    const hex = (rgb: typeof actual) =>
+      '#' +
+      [rgb.r, rgb.g, rgb.b]
+        .map((channel) => channel.toString(16).padStart(2, '0'))
+        .join('');
+    return `pixel ${x},${y} at ${formatTime(assertion.at ?? 0)} is ${hex(actual)}, expected ${hex(color)} (±${tolerance})`;
+  }
+
+  return undefined;
+}
+
+/**
+ * Evaluates the project's assertions. Only what's needed is rendered:
+ * text and duration checks use the timeline, pixel checks render one frame
+ * per distinct time.
+ * @param outputName - Only check assertions of this output
+ * @param tmpDir - Directory for the rendered frames
+ * @param between - Only check assertions at a time between these markers
+ */
+export async function runAssertions(
+  project: Project,
+  tmpDir: string,
+  outputName?: string,
+  between?: [string, string],
+): Promise<AssertionResult[]> {
+  const defaultOutput = Array.from(project.getOutputs().keys())[0];
+
+  // Assertions by output, the timeline is built once per output
+  const byOutput = new Map<string, Assertion[]>();
+  for (const assertion of project.getAssertions()) {
+    const name = assertion.output ?? defaultOutput;
+    if (outputName && name !== outputName) {
+      continue;
+    }
+    byOutput.set(name, [...(byOutput.get(name) ?? []), assertion]);
+  }
+
+  const results: AssertionResult[] = [];
+  for (const [name, outputAssertions] of byOutput) {
+    const output = project.getOutput(name);
+    if (!output) {
+      throw new Error(`Output "${name}" not found`);
+    }
+
+    let filter = (await project.build(name)).render();
+
+    // Marker times are known once the timeline is built, duration checks
+    // (no "at") cover the whole timeline and are left out of a region
+    let assertions = outputAssertions;
+    if (between) {
+      const range = project.getMarkerRange(...between);
+      assertions = assertions.filter(
+        ({ at }) => at !== undefined && at >= range.start && at < range.end,
+      );
+    }
+
+    const needsFrames = assertions.some((assertion) => assertion.pixel);
+    if (needsFrames && !output.format) {
+      await project.renderContainers(name);
+      await project.renderApps(name);
+      // rebuilt with the rendered containers and apps
+      filter = (await project.build(name)).render();
+    }
+
+    const frames = new Map<number, Buffer>();
+    const getFrame = async (time: number): Promise<Buffer> => {
+      let frame = frames.get(time);
+      if (!frame) {
+        const framePath = resolve(tmpDir, `frame_${name}_${time}.rgb`);
+        await runFFMpeg(
+          makeFrameFFmpegCommand(
+            project.getAssetManager(),
+            output,
+            filter,
+            time,
+            framePath,
+          ),
+          { silent: true },
+        );
+        frame = readFileSync(framePath);
+        frames.set(time, frame);
+      }
+      return frame;
+    };
+
+    for (const assertion of assertions) {
+      const failures: string[] = [];
+      const fail = (failure: string | undefined) => {
+        if (failure) {
+          failures.push(failure);
+        }
+      };
+
+      fail(checkDuration(project, assertion));
+      fail(checkText(project, assertion));
+
+      if (assertion.pixel && assertion.at !== undefined) {
+        if (output.format) {
+          fail(`output "${name}" is audio-only, pixels can't be checked`);
+        } else {
+          fail(checkPixel(await getFrame(assertion.at), output, assertion));
+        }
+      }
+
+      results.push({ assertion, output: name, failures });
+    }
+  }
+
+  return results;
+}
@@ -0,0 +1,122 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { mkdtempSync, rmSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import { resolve } from 'path';
+import {
+  loadAssetLibrary,
+  parseAssetRef,
+  resolveLibraryAsset,
+} from './asset-library';
+import { HTMLParser } from './html-parser';
+import { HTMLProjectParser } from './html-project-parser';
+
+describe('Asset library', () => {
+  let dir: string;
+
+  beforeEach(() => {
+    dir = mkdtempSync(resolve(tmpdir(), 'staticstripes-library-'));
+    writeFileSync(
+      resolve(dir, 'brand.html'),
+      `<assets>
+        <asset data-name="logo" data-path="./logo.png" data-author="Brand" />
+        <asset data-name="jingle" data-path="./jingle.mp3" />
+      </assets>`,
+    );
+    writeFileSync(
+      resolve(dir, 'channel.html'),
+      `<assets><asset data-name="logo" data-path="./channel-logo.png" /></assets>`,
+    );
+  });
+
+  afterEach(() => {
+    rmSync(dir, { recursive: true, force: true });
+  });
+
+  it('should split asset references', () => {
+    expect(parseAssetRef('lib:logo')).toEqual({
+      namespace: 'lib',
+      name: 'logo',
+    });
+    expect(parseAssetRef('logo')).toEqual({ name: 'logo' });
+  });
+
+  it('should resolve references by namespace and in library order', () => {
+    const libraries = [
+      loadAssetLibrary('channel', resolve(dir, 'channel.html')),
+      loadAssetLibrary('brand', resolve(dir, 'brand.html')),
+    ];
+
+    expect(
+      resolveLibraryAsset(libraries, 'brand:logo').library.namespace,
+    ).toBe('brand');
+    // without a namespace, the first library that has the asset wins
+    expect(
+      resolveLibraryAsset(libraries, 'logo').library.namespace,
+    ).toBe('channel');
+    expect(
+      resolveLibraryAsset(libraries, 'jingle').library.namespace,
+    ).toBe('brand');
+  });
+
+  it('should explain unknown namespaces and assets', () => {
+    const libraries = [loadAssetLibrary('brand', resolve(dir, 'brand.html'))];
+
+    expect(() => resolveLibraryAsset(libraries, 'lib:logo')).toThrow(
+      'Unknown asset library "lib" in ref="lib:logo", available: brand',
+    );
+    expect(() => resolveLibraryAsset(libraries, 'brand:intro')).toThrow(
+      'Asset "intro" not found in library "brand"',
+    );
+    expect(() => loadAssetLibrary('x', resolve(dir, 'missing.html'))).toThrow(
+      'Asset library "x" not found',
+    );
+  });
+
+  it('should report references to unknown libraries when parsing', async () => {
+    const parser = new HTMLProjectParser(
+      new HTMLParser().parse(`
+        <title>Test</title>
+        <library name="brand" src="./brand.html" />
+        <assets>
+          <asset ref="lib:logo" />
+        </assets>
+      `),
+      resolve(dir, 'project.html'),
+      { mode: 'strict', libraries: {} },
+    );
+
+    const error = await parser.parse().catch((e: Error) => e);
+    expect(error).toBeInstanceOf(Error);
+    expect((error as Error).message).toContain(
+      'Unknown asset library "lib" in ref="lib:logo", available: brand',
+    );
+  });
+
+  it('should report a missing library once and keep the others', async () => {
+    const parser = new HTMLProjectParser(
+      new HTMLParser().parse(`
+        <title>Test</title>
+        <library name="gone" src="./gone.html" />
+        <library name="brand" src="./brand.html" />
+        <assets>
+          <asset ref="gone:logo" />
+          <asset ref="gone:jingle" />
+          <asset ref="brand:logo" />
+          <asset ref="intro" />
+        </assets>
+      `),
+      resolve(dir, 'project.html'),
+      { mode: 'strict', libraries: {} },
+    );
+
+    const error = await parser.parse().catch((e: Error) => e);
+    const message = (error as Error).message;
+    expect(message.split('Asset library "gone" not found')).toHaveLength(2);
+    // refs into the library that failed to load are not reported again
+    expect(message).not.toContain('gone:');
+    expect(message).not.toContain('brand:logo');
+    expect(message).toContain(
+      'Asset "intro" not found in any asset library (ref="intro"), not loaded: gone',
+    );
+  });
+});
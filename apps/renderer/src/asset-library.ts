@@ -0,0 +1,113 @@
+import { existsSync, readFileSync } from 'fs';
+import { dirname } from 'path';
+import { HTMLParser } from './html-parser';
+import { ASTNode, Element } from './type';
+
+/**
+ * A shared asset library: an HTML file with <asset> elements (e.g. the brand
+ * assets of a channel), referenced by several projects with
+ * <asset ref="<namespace>:<name>" />
+ */
+export type AssetLibrary = {
+  namespace: string; // e.g. "lib"
+  path: string; // absolute path of the library file
+  dir: string; // library asset paths are relative to it
+  assets: Map<string, Element>; // <asset> elements by name
+};
+
+const NAMESPACE_PATTERN = /^[a-zA-Z0-9_-]+$/;
+
+export function isValidLibraryNamespace(namespace: string): boolean {
+  return NAMESPACE_PATTERN.test(namespace);
+}
+
+/**
+ * Splits an asset reference: "lib:logo" or "logo" (any library)
+ */
+export function parseAssetRef(ref: string): {
+  namespace?: string;
+  name: string;
+} {
+  const separator = ref.indexOf(':');
+  if (separator < 0) {
+    return { name: ref.trim() };
+  }
+  return {
+    namespace: ref.slice(0, separator).trim(),
+    name: ref.slice(separator + 1).trim(),
+  };
+}
+
+/**
+ * Loads a library file and indexes its <asset> elements by name
+ * (data-name, id or name, like in project.html)
+ */
+export function loadAssetLibrary(
+  namespace: string,
+  path: string,
+): AssetLibrary {
+  if (!existsSync(path)) {
+    throw new Error(`Asset library "${namespace}" not found at ${path}`);
+  }
+
+  const parsed = new HTMLParser().parse(readFileSync(path, 'utf-8'));
+  const assets = new Map<string, Element>();
+
+  const traverse = (node: ASTNode) => {
+    if (node.type === 'tag') {
+      const element = node as Element;
+      if (element.name === 'asset') {
+        const name =
+          element.attribs['data-name'] ||
+          element.attribs.id ||
+          element.attribs.name;
+        if (name && !assets.has(name)) {
+          assets.set(name, element);
+        }
+      }
+    }
+
+    if ('children' in node && node.children) {
+      for (const child of node.children) {
+        traverse(child);
+      }
+    }
+  };
+  traverse(parsed.ast);
+
+  return { namespace, path, dir: dirname(path), assets };
+}
+
+/**
+ * Finds the library asset of a reference. With a namespace only that
+ * library is searched, otherwise the libraries in resolution order
+ * (first match wins).
+ * @throws Error if the namespace or the asset is unknown
+ */
+export function resolveLibraryAsset(
+  libraries: AssetLibrary[],
+  ref: string,
+): { library: AssetLibrary; element: Element } {
+  const { namespace, name } = parseAssetRef(ref);
+
+  const candidates = namespace
+    ? libraries.filter((library) => library.namespace === namespace)
+    : libraries;
+  if (namespace && candidates.length === 0) {
+    const available = libraries.map((library) => library.namespace);
+    throw new Error(
+      `Unknown asset library "${namespace}" in ref="${ref}"${available.length > 0 ? `, available: ${available.join(', ')}` : ', no libraries are defined'}`,
+    );
+  }
+
+  for (const library of candidates) {
+    const element = library.assets.get(name);
+    if (element) {
+      return { library, element };
+    }
+  }
+
+  throw new Error(
+    `Asset "${name}" not found in ${namespace ? `library "${namespace}"` : 'any asset library'} (ref="${ref}")`,
+  );
+}
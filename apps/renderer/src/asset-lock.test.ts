@@ -0,0 +1,85 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { mkdtempSync, rmSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import { resolve } from 'path';
+import { makeAssetLock, verifyAssetLock, diffAssetLocks } from './asset-lock';
+import { Asset } from './type';
+
+describe('Asset lock', () => {
+  let projectDir: string;
+
+  const makeAsset = (name: string, file: string): Asset => ({
+    name,
+    path: resolve(projectDir, file),
+    type: 'audio',
+    duration: 1000,
+    width: 0,
+    height: 0,
+    rotation: 0,
+    hasVideo: false,
+    hasAudio: true,
+  });
+
+  beforeEach(() => {
+    projectDir = mkdtempSync(resolve(tmpdir(), 'staticstripes-lock-'));
+    writeFileSync(resolve(projectDir, 'music.mp3'), 'original');
+  });
+
+  afterEach(() => {
+    rmSync(projectDir, { recursive: true, force: true });
+  });
+
+  it('should lock relative paths, checksums and metadata', async () => {
+    const lock = await makeAssetLock(projectDir, [
+      makeAsset('music', 'music.mp3'),
+    ]);
+
+    expect(lock.assets.music.path).toBe('music.mp3');
+    expect(lock.assets.music.size).toBe(8);
+    expect(lock.assets.music.sha256).toMatch(/^[0-9a-f]{64}$/);
+    expect(lock.assets.music.duration).toBe(1000);
+  });
+
+  it('should pass verification when nothing changed', async () => {
+    const assets = [makeAsset('music', 'music.mp3')];
+    const lock = await makeAssetLock(projectDir, assets);
+
+    expect(await verifyAssetLock(projectDir, assets, lock)).toEqual([]);
+  });
+
+  it('should report swapped files of the same size', async () => {
+    const assets = [makeAsset('music', 'music.mp3')];
+    const lock = await makeAssetLock(projectDir, assets);
+
+    writeFileSync(resolve(projectDir, 'music.mp3'), 'swapped!');
+
+    const problems = await verifyAssetLock(projectDir, assets, lock);
+    expect(problems).toEqual(['Asset "music" changed: checksum mismatch']);
+  });
+
+  it('should report changed metadata, added and removed assets', async () => {
+    const lock = await makeAssetLock(projectDir, [
+      makeAsset('music', 'music.mp3'),
+    ]);
+
+    writeFileSync(resolve(projectDir, 'voice.mp3'), 'voice');
+    const assets = [
+      { ...makeAsset('music', 'music.mp3'), duration: 2000 },
+      makeAsset('voice', 'voice.mp3'),
+    ];
+
+    const problems = await verifyAssetLock(projectDir, assets, lock);
+    expect(problems).toContain(
+      'Asset "music" probes differently: duration is 2000, locked: 1000',
+    );
+    expect(problems).toContain('Asset "voice" is not in the lockfile');
+
+    const nextLock = await makeAssetLock(projectDir, [
+      makeAsset('voice', 'voice.mp3'),
+    ]);
+    expect(diffAssetLocks(lock, nextLock)).toEqual([
+      'Asset "voice" added',
+      'Asset "music" removed',
+    ]);
+  });
+});
@@ -0,0 +1,215 @@
+import { createHash } from 'crypto';
+import { createReadStream, existsSync, readFileSync, statSync } from 'fs';
+import { relative, resolve } from 'path';
+import { Asset } from './type';
+import { writeFile } from './lib/file';
+
+export const ASSET_LOCK_FILE_NAME = 'staticstripes.lock.json';
+
+/**
+ * Checksum and probed metadata of an asset file at the time of locking
+ */
+export type LockedAsset = {
+  path: string; // relative to the project directory
+  sha256: string;
+  size: number; // bytes
+  type: Asset['type'];
+  duration: number; // ms
+  width: number;
+  height: number;
+  rotation: number;
+  hasVideo: boolean;
+  hasAudio: boolean;
+};
+
+/**
+ * Contents of staticstripes.lock.json, next to project.html
+ */
+export type AssetLock = {
+  version: 1;
+  assets: Record<string, LockedAsset>; // by asset name
+};
+
+/**
+ * Calculates the sha256 of a file, streaming it (assets can be large)
+ */
+export function hashFile(path: string): Promise<string> {
+  return new Promise((resolvePromise, reject) => {
+    const hash = createHash('sha256');
+    createReadStream(path)
+      .on('data', (chunk) => hash.update(chunk))
+      .on('end', () => resolvePromise(hash.digest('hex')))
+      .on('error', reject);
+  });
+}
+
+/**
+ * Sub-clips share the file of their source, only real files are locked
+ */
+function getLockableAssets(assets: Asset[]): Asset[] {
+  return assets.filter((asset) => !asset.subclip);
+}
+
+function getLockedMetadata(
+  asset: Asset,
+): Omit<LockedAsset, 'path' | 'sha256' | 'size'> {
+  return {
+    type: asset.type,
+    duration: asset.duration,
+    width: asset.width,
+    height: asset.height,
+    rotation: asset.rotation,
+    hasVideo: asset.hasVideo,
+    hasAudio: asset.hasAudio,
+  };
+}
+
+/**
+ * Creates a lock of the current state of the project's asset files
+ */
+export async function makeAssetLock(
+  projectDir: string,
+  assets: Asset[],
+): Promise<AssetLock> {
+  const lock: AssetLock = { version: 1, assets: {} };
+
+  for (const asset of getLockableAssets(assets)) {
+    if (!existsSync(asset.path)) {
+      throw new Error(`Asset "${asset.name}" not found at ${asset.path}`);
+    }
+
+    lock.assets[asset.name] = {
+      path: relative(projectDir, asset.path),
+      sha256: await hashFile(asset.path),
+      size: statSync(asset.path).size,
+      ...getLockedMetadata(asset),
+    };
+  }
+
+  return lock;
+}
+
+/**
+ * Loads the lockfile of a project, undefined if there is none
+ * @throws Error if the lockfile is not valid
+ */
+export function loadAssetLock(projectDir: string): AssetLock | undefined {
+  const path = resolve(projectDir, ASSET_LOCK_FILE_NAME);
+  if (!existsSync(path)) {
+    return undefined;
+  }
+
+  let raw: any;
+  try {
+    raw = JSON.parse(readFileSync(path, 'utf-8'));
+  } catch (error) {
+    throw new Error(
+      `Invalid lockfile ${path}: ${error instanceof Error ? error.message : String(error)}`,
+    );
+  }
+
+  if (raw.version !== 1 || typeof raw.assets !== 'object' || !raw.assets) {
+    throw new Error(`Invalid lockfile ${path}: unsupported format`);
+  }
+
+  return raw as AssetLock;
+}
+
+export function writeAssetLock(projectDir: string, lock: AssetLock): string {
+  const path = resolve(projectDir, ASSET_LOCK_FILE_NAME);
+  writeFile(path, Buffer.from(JSON.stringify(lock, null, 2) + '\n'));
+  return path;
+}
+
+/**
+ * Compares the project's asset files with the lock
+ * @returns Human-readable differences, empty if the assets match the lock
+ */
+export async function verifyAssetLock(
+  projectDir: string,
+  assets: Asset[],
+  lock: AssetLock,
+): Promise<string[]> {
+  const problems: string[] = [];
+  const lockableAssets = getLockableAssets(assets);
+
+  for (const asset of lockableAssets) {
+    const locked = lock.assets[asset.name];
+    if (!locked) {
+      problems.push(`Asset "${asset.name}" is not in the lockfile`);
+      continue;
+    }
+
+    const path = relative(projectDir, asset.path);
+    if (path !== locked.path) {
+      problems.push(
+        `Asset "${asset.name}" points to ${path}, locked: ${locked.path}`,
+      );
+    }
+
+    if (!existsSync(asset.path)) {
+      problems.push(`Asset "${asset.name}" not found at ${asset.path}`);
+      continue;
+    }
+
+    // Size first, it's cheap
+    const size = statSync(asset.path).size;
+    if (size !== locked.size) {
+      problems.push(
+        `Asset "${asset.name}" changed: ${size} bytes, locked: ${locked.size} bytes`,
+      );
+      continue;
+    }
+
+    if ((await hashFile(asset.path)) !== locked.sha256) {
+      problems.push(`Asset "${asset.name}" changed: checksum mismatch`);
+      continue;
+    }
+
+    const metadata = getLockedMetadata(asset);
+    for (const key of Object.keys(metadata) as (keyof typeof metadata)[]) {
+      if (metadata[key] !== locked[key]) {
+        problems.push(
+          `Asset "${asset.name}" probes differently: ${key} is ${metadata[key]}, locked: ${locked[key]}`,
+        );
+      }
+    }
+  }
+
+  const names = new Set(lockableAssets.map((asset) => asset.name));
+  for (const name of Object.keys(lock.assets)) {
+    if (!names.has(name)) {
+      problems.push(`Locked asset "${name}" is no longer in the project`);
+    }
+  }
+
+  return problems;
+}
+
+/**
+ * Lists what changed between two locks, e.g. to show what re-locking updates
+ */
+export function diffAssetLocks(previous: AssetLock, next: AssetLock): string[] {
+  const changes: string[] = [];
+
+  for (const [name, locked] of Object.entries(next.assets)) {
+    const previousLocked = previous.assets[name];
+    if (!previousLocked) {
+      changes.push(`Asset "${name}" added`);
+    } else if (previousLocked.sha256 !== locked.sha256) {
+      changes.push(`Asset "${name}" changed`);
+    } else if (previousLocked.path !== locked.path) {
+      changes.push(
+        `Asset "${name}" moved from ${previousLocked.path} to ${locked.path}`,
+      );
+    }
+  }
+
+  for (const name of Object.keys(previous.assets)) {
+    if (!next.assets[name]) {
+      changes.push(`Asset "${name}" removed`);
+    }
+  }
+
+  return changes;
+}
@@ -0,0 +1,57 @@
+import { describe, it, expect } from 'vitest';
+import { AssetManager } from './asset-manager';
+import { makeFrameFFmpegCommand } from './ffmpeg';
+import { Asset, Output } from './type';
+
+describe('AssetManager', () => {
+  const makeAsset = (name: string, path: string, width: number): Asset => ({
+    name,
+    path,
+    type: 'image',
+    duration: 0,
+    width,
+    height: (width / 16) * 9,
+    rotation: 0,
+    hasVideo: true,
+    hasAudio: false,
+  });
+
+  it('should replace a container rendered again for another output', () => {
+    const assetManager = new AssetManager([
+      makeAsset('clip', '/tmp/clip.png', 1920),
+    ]);
+
+    // runAssertions renders the containers once per output
+    assetManager.addVirtualAsset(
+      makeAsset('title', '/tmp/cache/title_1080.png', 1920),
+    );
+    assetManager.addVirtualAsset(
+      makeAsset('title', '/tmp/cache/title_720.png', 1280),
+    );
+
+    expect(Array.from(assetManager.getAssetIndexMap())).toEqual([
+      ['clip', 0],
+      ['title', 1],
+    ]);
+    expect(assetManager.getAssets()).toHaveLength(2);
+    expect(assetManager.getAssetByName('title')?.width).toBe(1280);
+
+    const output: Output = {
+      name: 'proxy',
+      path: '/tmp/proxy.mp4',
+      resolution: { width: 1280, height: 720 },
+      fps: 30,
+    };
+    const command = makeFrameFFmpegCommand(
+      assetManager,
+      output,
+      '[1:v]null[outv]',
+      0,
+      '/tmp/frame.rgb',
+    );
+    expect(command.match(/-i "[^"]*"/g)).toEqual([
+      '-i "/tmp/clip.png"',
+      '-i "/tmp/cache/title_720.png"',
+    ]);
+  });
+});
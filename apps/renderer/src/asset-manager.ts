@@ -3,11 +3,20 @@ import { Asset } from './type';
 
 export class AssetManager {
   private assetIndexMap: Map<string, number> = new Map();
+  private inputCount: number = 0; // number of distinct ffmpeg inputs
 
   constructor(private assets: Asset[]) {
-    let index = 0;
     for (const asset of assets) {
-      this.assetIndexMap.set(asset.name, index++);
+      // Sub-clips read from the same input as their source asset
+      const sourceIndex = asset.subclip
+        ? this.assetIndexMap.get(asset.subclip.from)
+        : undefined;
+
+      if (sourceIndex !== undefined) {
+        this.assetIndexMap.set(asset.name, sourceIndex);
+      } else {
+        this.assetIndexMap.set(asset.name, this.inputCount++);
+      }
     }
   }
 
@@ -47,13 +56,22 @@ export class AssetManager {
 
   /**
    * Adds a virtual asset (e.g., rendered container screenshot)
+   * A virtual asset of the same name (rendered for another output) is
+   * replaced and keeps its index, so the inputs stay contiguous
    */
   public addVirtualAsset(asset: Asset): void {
+    const existing = this.assets.findIndex(
+      (assetItem) => assetItem.name === asset.name,
+    );
+    if (existing >= 0 && this.assetIndexMap.has(asset.name)) {
+      this.assets[existing] = asset;
+      return;
+    }
+
     // Add to assets array
     this.assets.push(asset);
 
     // Assign next available index
-    const nextIndex = this.assetIndexMap.size;
-    this.assetIndexMap.set(asset.name, nextIndex);
+    this.assetIndexMap.set(asset.name, this.inputCount++);
   }
 }
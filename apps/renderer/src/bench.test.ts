@@ -0,0 +1,133 @@
+import { describe, it, expect } from 'vitest';
+import {
+  BENCH_PROFILES,
+  BenchReport,
+  BenchResult,
+  compareBenchReports,
+  getBenchFFmpegArgs,
+  median,
+} from './bench';
+import { Output } from './type';
+
+const makeResult = (
+  project: string,
+  total: number,
+  encodeFps: number,
+  extra: Partial<BenchResult> = {},
+): BenchResult => ({
+  project,
+  output: 'youtube',
+  profile: 'preview',
+  runs: [],
+  median: { stages: {}, total, encodeFps },
+  ...extra,
+});
+
+const makeReport = (results: BenchResult[]): BenchReport => ({
+  date: '2025-03-14T09:26:53.000Z',
+  node: 'v22.0.0',
+  platform: 'linux x64',
+  runs: 3,
+  results,
+});
+
+describe('Bench', () => {
+  it('should take the median of the runs', () => {
+    expect(median([])).toBe(0);
+    expect(median([7])).toBe(7);
+    expect(median([300, 100, 200])).toBe(200);
+    expect(median([400, 100, 300, 200])).toBe(250);
+  });
+
+  it('should report slowdowns above the threshold as regressions', () => {
+    const baseline = makeReport([
+      makeResult('intro', 1000, 100),
+      makeResult('slideshow', 2000, 50),
+      makeResult('podcast', 500, 0),
+    ]);
+    const report = makeReport([
+      makeResult('intro', 1050, 98),
+      makeResult('slideshow', 2400, 50),
+      makeResult('podcast', 500, 0),
+    ]);
+
+    const comparisons = compareBenchReports(report, baseline, 0.1);
+
+    expect(comparisons.map(({ key, regression }) => [key, regression])).toEqual(
+      [
+        ['intro/youtube/preview', false],
+        ['slideshow/youtube/preview', true],
+        ['podcast/youtube/preview', false],
+      ],
+    );
+    expect(comparisons[0].totalChange).toBeCloseTo(0.05);
+    expect(comparisons[0].encodeFpsChange).toBeCloseTo(-0.02);
+    expect(comparisons[1].totalChange).toBeCloseTo(0.2);
+    // audio-only outputs have no encode fps, nothing to compare
+    expect(comparisons[2].encodeFpsChange).toBe(0);
+  });
+
+  it('should report a drop of the encode fps as a regression', () => {
+    const comparisons = compareBenchReports(
+      makeReport([makeResult('intro', 1000, 80)]),
+      makeReport([makeResult('intro', 1000, 100)]),
+      0.1,
+    );
+
+    expect(comparisons[0].encodeFpsChange).toBeCloseTo(-0.2);
+    expect(comparisons[0].regression).toBe(true);
+  });
+
+  it('should skip results missing in either report or without a median', () => {
+    const comparisons = compareBenchReports(
+      makeReport([
+        makeResult('new', 1000, 100),
+        makeResult('failed', 0, 0, { median: undefined, error: 'boom' }),
+        makeResult('other-profile', 1000, 100, { profile: 'production' }),
+        makeResult('zero', 1000, 100),
+      ]),
+      makeReport([
+        makeResult('failed', 1000, 100),
+        makeResult('other-profile', 1000, 100),
+        makeResult('zero', 0, 0),
+      ]),
+      0.1,
+    );
+
+    // a zero baseline can't give a relative change
+    expect(comparisons).toEqual([
+      {
+        key: 'zero/youtube/preview',
+        totalChange: 0,
+        encodeFpsChange: 0,
+        regression: false,
+      },
+    ]);
+  });
+
+  it('should apply the profile to plain video outputs only', () => {
+    const preview = BENCH_PROFILES.find(({ name }) => name === 'preview')!;
+    const output: Output = {
+      name: 'youtube',
+      path: './output/youtube.mp4',
+      resolution: { width: 1920, height: 1080 },
+      fps: 30,
+    };
+
+    expect(getBenchFFmpegArgs(preview, output)).toBe(preview.ffmpegArgs);
+    expect(
+      getBenchFFmpegArgs(preview, {
+        ...output,
+        path: './output/podcast.mp3',
+        format: 'mp3',
+      }),
+    ).toBe('-c:a libmp3lame -b:a 192k');
+    expect(
+      getBenchFFmpegArgs(preview, {
+        ...output,
+        path: './output/overlay.mov',
+        alpha: true,
+      }),
+    ).toContain('-pix_fmt yuva444p10le');
+  });
+});
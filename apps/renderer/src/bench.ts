@@ -0,0 +1,302 @@
+import { existsSync, readdirSync } from 'fs';
+import { resolve, basename, extname } from 'path';
+import { HTMLParser } from './html-parser';
+import { HTMLProjectParser } from './html-project-parser';
+import {
+  makeFFmpegCommandForAssets,
+  getDefaultFFmpegArgs,
+  runFFMpeg,
+} from './ffmpeg';
+import { getAssetDuration } from './ffprobe';
+import { getCacheStats, resetCacheStats, CacheStats } from './cache-stats';
+import { Output } from './type';
+
+/**
+ * Encoding profile a benchmark renders with
+ */
+export type BenchProfile = {
+  name: string;
+  description: string;
+  ffmpegArgs?: string; // arguments of plain video outputs, default if not set
+};
+
+export const BENCH_PROFILES: BenchProfile[] = [
+  {
+    name: 'preview',
+    description: 'x264 ultrafast, low quality',
+    ffmpegArgs:
+      '-c:v libx264 -pix_fmt yuv420p -preset ultrafast -crf 28 -c:a aac -b:a 128k',
+  },
+  {
+    name: 'default',
+    description: 'default encoding arguments',
+  },
+];
+
+/**
+ * Encoding arguments of a benchmark render. The profile only applies to
+ * plain video outputs, audio-only and alpha outputs keep their own codecs.
+ */
+export function getBenchFFmpegArgs(
+  profile: BenchProfile,
+  output: Output,
+): string {
+  return profile.ffmpegArgs && !output.format && !output.alpha
+    ? profile.ffmpegArgs
+    : getDefaultFFmpegArgs(output);
+}
+
+export type BenchStage = 'parse' | 'containers' | 'apps' | 'build' | 'encode';
+
+export type BenchRun = {
+  stages: Partial<Record<BenchStage, number>>; // ms
+  total: number; // ms
+  mediaDuration: number; // ms, duration of the rendered file
+  encodeFps: number; // rendered frames per second of encoding (0 for audio)
+  cache: Record<string, CacheStats>;
+};
+
+export type BenchResult = {
+  project: string; // example directory name
+  output: string;
+  profile: string;
+  runs: BenchRun[];
+  median?: {
+    stages: Partial<Record<BenchStage, number>>;
+    total: number;
+    encodeFps: number;
+  };
+  cacheHitRate?: number; // 0..1 over all runs, undefined if nothing was cached
+  error?: string;
+};
+
+export type BenchReport = {
+  date: string;
+  node: string;
+  platform: string;
+  runs: number;
+  results: BenchResult[];
+};
+
+/**
+ * Finds the example projects (directories with a project.html)
+ */
+export function findExampleProjects(examplesDir: string): string[] {
+  if (!existsSync(examplesDir)) {
+    return [];
+  }
+
+  return readdirSync(examplesDir, { withFileTypes: true })
+    .filter((entry) => entry.isDirectory())
+    .map((entry) => resolve(examplesDir, entry.name))
+    .filter((dir) => existsSync(resolve(dir, 'project.html')))
+    .sort();
+}
+
+/**
+ * Median of the values, the mean of the two middle ones for an even count
+ */
+export function median(values: number[]): number {
+  if (values.length === 0) {
+    return 0;
+  }
+  const sorted = [...values].sort((a, b) => a - b);
+  const middle = Math.floor(sorted.length / 2);
+  return sorted.length % 2
+    ? sorted[middle]
+    : (sorted[middle - 1] + sorted[middle]) / 2;
+}
+
+/**
+ * Renders one output of a project once, measuring every stage
+ */
+async function runOnce(
+  projectDir: string,
+  outputName: string | undefined,
+  profile: BenchProfile,
+  tmpDir: string,
+): Promise<{ outputName: string; run: BenchRun }> {
+  const stages: Partial<Record<BenchStage, number>> = {};
+  const measure = async <T>(
+    stage: BenchStage,
+    fn: () => Promise<T>,
+  ): Promise<T> => {
+    const start = Date.now();
+    const result = await fn();
+    stages[stage] = Date.now() - start;
+    return result;
+  };
+
+  resetCacheStats();
+  const startTime = Date.now();
+
+  const projectFilePath = resolve(projectDir, 'project.html');
+  const project = await measure('parse', async () =>
+    new HTMLProjectParser(
+      await new HTMLParser().parseFile(projectFilePath),
+      projectFilePath,
+    ).parse(),
+  );
+
+  const name = outputName ?? Array.from(project.getOutputs().keys())[0];
+  const output = name ? project.getOutput(name) : undefined;
+  if (!name || !output) {
+    throw new Error(`Output "${outputName ?? ''}" not found`);
+  }
+
+  if (!output.format) {
+    await measure('containers', () => project.renderContainers(name));
+    await measure('apps', () => project.renderApps(name));
+  }
+
+  const filter = await measure('build', async () =>
+    (await project.build(name)).render(),
+  );
+
+  // Never overwrite the example's own output
+  const benchOutput = {
+    ...output,
+    path: resolve(
+      tmpDir,
+      `${basename(projectDir)}_${name}_${profile.name}${extname(output.path)}`,
+    ),
+  };
+  const ffmpegCommand = makeFFmpegCommandForAssets(
+    project.getAssetManager(),
+    benchOutput,
+    filter,
+    getBenchFFmpegArgs(profile, output),
+  );
+  await measure('encode', () => runFFMpeg(ffmpegCommand, { silent: true }));
+
+  const total = Date.now() - startTime;
+  const mediaDuration = await getAssetDuration(benchOutput.path);
+  const frames = output.format ? 0 : (mediaDuration / 1000) * output.fps;
+  const encodeSeconds = (stages.encode ?? 0) / 1000;
+
+  return {
+    outputName: name,
+    run: {
+      stages,
+      total,
+      mediaDuration,
+      encodeFps: encodeSeconds > 0 ? frames / encodeSeconds : 0,
+      cache: getCacheStats(),
+    },
+  };
+}
+
+/**
+ * Renders a project with a profile several times and aggregates the runs
+ */
+export async function benchProject(options: {
+  projectDir: string;
+  outputName?: string;
+  profile: BenchProfile;
+  runs: number;
+  tmpDir: string;
+  onRun?: (run: BenchRun, index: number) => void;
+}): Promise<BenchResult> {
+  const { projectDir, profile, runs, tmpDir } = options;
+  const result: BenchResult = {
+    project: basename(projectDir),
+    output: options.outputName ?? '',
+    profile: profile.name,
+    runs: [],
+  };
+
+  try {
+    for (let i = 0; i < runs; i++) {
+      const { outputName, run } = await runOnce(
+        projectDir,
+        options.outputName,
+        profile,
+        tmpDir,
+      );
+      result.output = outputName;
+      result.runs.push(run);
+      options.onRun?.(run, i);
+    }
+  } catch (error) {
+    result.error = error instanceof Error ? error.message : String(error);
+    return result;
+  }
+
+  const stageNames = new Set(
+    result.runs.flatMap((run) => Object.keys(run.stages) as BenchStage[]),
+  );
+  const stages: Partial<Record<BenchStage, number>> = {};
+  for (const stage of stageNames) {
+    stages[stage] = median(result.runs.map((run) => run.stages[stage] ?? 0));
+  }
+
+  result.median = {
+    stages,
+    total: median(result.runs.map((run) => run.total)),
+    encodeFps: median(result.runs.map((run) => run.encodeFps)),
+  };
+
+  let hits = 0;
+  let lookups = 0;
+  for (const run of result.runs) {
+    for (const entry of Object.values(run.cache)) {
+      hits += entry.hits;
+      lookups += entry.hits + entry.misses;
+    }
+  }
+  if (lookups > 0) {
+    result.cacheHitRate = hits / lookups;
+  }
+
+  return result;
+}
+
+export type BenchComparison = {
+  key: string; // project/output/profile
+  totalChange: number; // relative change of the median total time, e.g. 0.1 = 10% slower
+  encodeFpsChange: number; // relative change of the median encode fps, e.g. -0.1 = 10% slower
+  regression: boolean;
+};
+
+/**
+ * Compares a report with a baseline report, matching results by
+ * project, output and profile
+ * @param threshold - relative slowdown that counts as a regression (0.1 = 10%)
+ */
+export function compareBenchReports(
+  report: BenchReport,
+  baseline: BenchReport,
+  threshold: number,
+): BenchComparison[] {
+  const keyOf = (result: BenchResult) =>
+    `${result.project}/${result.output}/${result.profile}`;
+  const baselineByKey = new Map(
+    baseline.results.map((result) => [keyOf(result), result]),
+  );
+
+  const comparisons: BenchComparison[] = [];
+  for (const result of report.results) {
+    const previous = baselineByKey.get(keyOf(result));
+    if (!result.median || !previous?.median) {
+      continue;
+    }
+
+    const totalChange =
+      previous.median.total > 0
+        ? result.median.total / previous.median.total - 1
+        : 0;
+    const encodeFpsChange =
+      previous.median.encodeFps > 0
+        ? result.median.encodeFps / previous.median.encodeFps - 1
+        : 0;
+
+    comparisons.push({
+      key: keyOf(result),
+      totalChange,
+      encodeFpsChange,
+      regression: totalChange > threshold || encodeFpsChange < -threshold,
+    });
+  }
+
+  return comparisons;
+}
@@ -0,0 +1,83 @@
+import { execFileSync } from 'child_process';
+
+/**
+ * Built-in variables of a render, usable in containers, app parameters,
+ * titles and upload descriptions as {{ .Name }}, so review cuts are
+ * self-identifying
+ */
+export type BuildInfo = {
+  RenderDate: string; // e.g. "2025-03-14 09:26", local time
+  GitCommit: string; // short hash of the project repository, "unknown" outside of git
+  ProjectVersion: string; // text of <version>, empty if not set
+};
+
+export const BUILD_INFO_VARIABLES = [
+  'RenderDate',
+  'GitCommit',
+  'ProjectVersion',
+] as const;
+
+const VARIABLE_PATTERN = /\{\{\s*\.([A-Za-z]+)\s*\}\}/g;
+
+export function hasBuildInfoVariables(text: string): boolean {
+  return text.includes('{{');
+}
+
+/**
+ * Formats a date as "YYYY-MM-DD HH:MM" in local time
+ */
+export function formatRenderDate(date: Date): string {
+  const pad = (value: number) => String(value).padStart(2, '0');
+  return (
+    `${date.getFullYear()}-${pad(date.getMonth() + 1)}-${pad(date.getDate())}` +
+    ` ${pad(date.getHours())}:${pad(date.getMinutes())}`
+  );
+}
+
+/**
+ * Short hash of the HEAD commit of the repository the directory is in
+ */
+export function getGitCommit(dir: string): string {
+  try {
+    return (
+      execFileSync('git', ['rev-parse', '--short', 'HEAD'], {
+        cwd: dir,
+        encoding: 'utf-8',
+        stdio: ['ignore', 'pipe', 'ignore'],
+      }).trim() || 'unknown'
+    );
+  } catch {
+    return 'unknown';
+  }
+}
+
+export function getBuildInfo(
+  projectDir: string,
+  projectVersion: string = '',
+  now: Date = new Date(),
+): BuildInfo {
+  return {
+    RenderDate: formatRenderDate(now),
+    GitCommit: getGitCommit(projectDir),
+    ProjectVersion: projectVersion,
+  };
+}
+
+/**
+ * Replaces {{ .Name }} variables with their values
+ * @returns The text and the names of unknown variables, which are kept as is
+ */
+export function interpolateBuildInfo(
+  text: string,
+  info: BuildInfo,
+): { text: string; unknown: string[] } {
+  const unknown: string[] = [];
+  const result = text.replace(VARIABLE_PATTERN, (match, name: string) => {
+    if (name in info) {
+      return info[name as keyof BuildInfo];
+    }
+    unknown.push(name);
+    return match;
+  });
+  return { text: result, unknown };
+}
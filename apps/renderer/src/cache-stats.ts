@@ -0,0 +1,42 @@
+/**
+ * Process-wide counters of render cache lookups, per cache namespace
+ * (e.g. "containers", "apps"), used to measure cache efficiency
+ */
+export type CacheStats = {
+  hits: number;
+  misses: number;
+};
+
+const stats = new Map<string, CacheStats>();
+
+function getOrCreate(namespace: string): CacheStats {
+  let entry = stats.get(namespace);
+  if (!entry) {
+    entry = { hits: 0, misses: 0 };
+    stats.set(namespace, entry);
+  }
+  return entry;
+}
+
+export function recordCacheHit(namespace: string): void {
+  getOrCreate(namespace).hits++;
+}
+
+export function recordCacheMiss(namespace: string): void {
+  getOrCreate(namespace).misses++;
+}
+
+/**
+ * Returns a snapshot of the counters
+ */
+export function getCacheStats(): Record<string, CacheStats> {
+  const result: Record<string, CacheStats> = {};
+  for (const [namespace, entry] of stats) {
+    result[namespace] = { ...entry };
+  }
+  return result;
+}
+
+export function resetCacheStats(): void {
+  stats.clear();
+}
@@ -0,0 +1,101 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import {
+  existsSync,
+  mkdirSync,
+  mkdtempSync,
+  rmSync,
+  utimesSync,
+  writeFileSync,
+} from 'fs';
+import { tmpdir } from 'os';
+import { basename, dirname, resolve } from 'path';
+import {
+  CachePrunePolicy,
+  clearCache,
+  getCacheUsage,
+  listCacheEntries,
+  parseAge,
+  parseByteSize,
+  selectPrunableEntries,
+} from './cache';
+
+const DAY = 24 * 60 * 60 * 1000;
+
+describe('Cache', () => {
+  let projectDir: string;
+  const now = Date.now();
+
+  // Writes a cache file last used the given number of days ago
+  const writeCacheFile = (file: string, size: number, daysAgo: number) => {
+    const path = resolve(projectDir, 'cache', file);
+    mkdirSync(dirname(path), { recursive: true });
+    writeFileSync(path, Buffer.alloc(size));
+    const time = (now - daysAgo * DAY) / 1000;
+    utimesSync(path, time, time);
+  };
+
+  beforeEach(() => {
+    projectDir = mkdtempSync(resolve(tmpdir(), 'staticstripes-cache-'));
+    writeCacheFile('containers/a.png', 100, 10);
+    writeCacheFile('containers/b.png', 200, 1);
+    writeCacheFile('conform/c.mkv', 1000, 5);
+    writeCacheFile('fonts/d.ttf', 50, 0);
+  });
+
+  afterEach(() => {
+    rmSync(projectDir, { recursive: true, force: true });
+  });
+
+  it('should parse sizes and ages', () => {
+    expect(parseByteSize('500')).toBe(500);
+    expect(parseByteSize('2G')).toBe(2 * 1024 ** 3);
+    expect(parseByteSize('1.5MB')).toBe(1.5 * 1024 ** 2);
+    expect(parseByteSize('lots')).toBeUndefined();
+
+    expect(parseAge('12h')).toBe(12 * 60 * 60 * 1000);
+    expect(parseAge('14d')).toBe(14 * DAY);
+    expect(parseAge('14')).toBeUndefined();
+  });
+
+  it('should sum up the cache per namespace', () => {
+    const usage = getCacheUsage(listCacheEntries(projectDir));
+
+    expect(
+      usage.map(({ namespace, files, size }) => [namespace, files, size]),
+    ).toEqual([
+      ['conform', 1, 1000],
+      ['containers', 2, 300],
+      ['fonts', 1, 50],
+    ]);
+    expect(listCacheEntries(projectDir, ['fonts'])).toHaveLength(1);
+  });
+
+  it('should prune old files, then the least recently used ones', () => {
+    const entries = listCacheEntries(projectDir);
+    const names = (policy: CachePrunePolicy) =>
+      selectPrunableEntries(entries, policy, now).map((entry) =>
+        basename(entry.path),
+      );
+
+    expect(names({ maxAge: 7 * DAY })).toEqual(['a.png']);
+    expect(names({ maxSize: 400 })).toEqual(['a.png', 'c.mkv']);
+    expect(names({ maxSize: 400, maxAge: 7 * DAY })).toEqual([
+      'a.png',
+      'c.mkv',
+    ]);
+    expect(names({ maxSize: 10_000 })).toEqual([]);
+  });
+
+  it('should clear namespaces or the whole cache', () => {
+    expect(clearCache(projectDir, ['containers'])).toBe(300);
+    expect(existsSync(resolve(projectDir, 'cache', 'containers'))).toBe(false);
+    expect(existsSync(resolve(projectDir, 'cache', 'fonts'))).toBe(true);
+
+    expect(() => clearCache(projectDir, ['..'])).toThrow(
+      'Invalid cache namespace ".."',
+    );
+
+    expect(clearCache(projectDir)).toBe(1050);
+    expect(existsSync(resolve(projectDir, 'cache'))).toBe(false);
+  });
+});
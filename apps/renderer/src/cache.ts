@@ -0,0 +1,224 @@
+import { existsSync, readdirSync, rmSync, statSync, unlinkSync } from 'fs';
+import { relative, resolve, sep } from 'path';
+
+/**
+ * The render cache of a project lives in <project>/cache, one directory
+ * per namespace: containers, apps, conform, fonts
+ */
+export type CacheEntry = {
+  namespace: string;
+  path: string;
+  size: number;
+  lastUsed: number; // ms, access time (or modification time if later)
+};
+
+export type CacheNamespaceUsage = {
+  namespace: string;
+  files: number;
+  size: number;
+  oldest?: number; // ms, last use of the least recently used file
+  newest?: number;
+};
+
+export type CachePrunePolicy = {
+  maxSize?: number; // bytes, least recently used files are removed above it
+  maxAge?: number; // ms, files not used for longer are removed
+};
+
+const SIZE_UNITS: Record<string, number> = {
+  '': 1,
+  k: 1024,
+  m: 1024 ** 2,
+  g: 1024 ** 3,
+  t: 1024 ** 4,
+};
+
+const AGE_UNITS: Record<string, number> = {
+  m: 60 * 1000,
+  h: 60 * 60 * 1000,
+  d: 24 * 60 * 60 * 1000,
+  w: 7 * 24 * 60 * 60 * 1000,
+};
+
+export function getCacheDir(projectDir: string): string {
+  return resolve(projectDir, 'cache');
+}
+
+/**
+ * Parses a size like "500M", "2G" or "1.5GB" (binary units)
+ * @returns Bytes, undefined if the value is not a size
+ */
+export function parseByteSize(value: string): number | undefined {
+  const match = value.trim().match(/^(\d+(?:\.\d+)?)\s*([kmgt]?)b?$/i);
+  if (!match) {
+    return undefined;
+  }
+  const unit = SIZE_UNITS[match[2].toLowerCase()];
+  return Math.round(parseFloat(match[1]) * unit);
+}
+
+/**
+ * Parses an age like "30m", "12h", "14d" or "2w"
+ * @returns Milliseconds, undefined if the value is not an age
+ */
+export function parseAge(value: string): number | undefined {
+  const match = value.trim().match(/^(\d+(?:\.\d+)?)\s*([mhdw])$/i);
+  if (!match) {
+    return undefined;
+  }
+  const unit = AGE_UNITS[match[2].toLowerCase()];
+  return Math.round(parseFloat(match[1]) * unit);
+}
+
+export function formatByteSize(bytes: number): string {
+  if (bytes >= 1024 ** 3) {
+    return `${(bytes / 1024 ** 3).toFixed(1)} GB`;
+  }
+  if (bytes >= 1024 ** 2) {
+    return `${(bytes / 1024 ** 2).toFixed(1)} MB`;
+  }
+  return `${(bytes / 1024).toFixed(1)} KB`;
+}
+
+/**
+ * Lists the files of the project cache, of all namespaces or the given ones.
+ * Files directly in the cache directory belong to the "other" namespace.
+ */
+export function listCacheEntries(
+  projectDir: string,
+  namespaces?: string[],
+): CacheEntry[] {
+  const cacheDir = getCacheDir(projectDir);
+  if (!existsSync(cacheDir)) {
+    return [];
+  }
+
+  const entries: CacheEntry[] = [];
+  const scan = (dir: string) => {
+    for (const entry of readdirSync(dir, { withFileTypes: true })) {
+      const path = resolve(dir, entry.name);
+      if (entry.isDirectory()) {
+        scan(path);
+        continue;
+      }
+
+      const parts = relative(cacheDir, path).split(sep);
+      const namespace = parts.length > 1 ? parts[0] : 'other';
+      if (namespaces && !namespaces.includes(namespace)) {
+        continue;
+      }
+
+      const stats = statSync(path);
+      entries.push({
+        namespace,
+        path,
+        size: stats.size,
+        lastUsed: Math.max(stats.atimeMs, stats.mtimeMs),
+      });
+    }
+  };
+  scan(cacheDir);
+
+  return entries;
+}
+
+/**
+ * Sums up the cache files per namespace, ordered by namespace
+ */
+export function getCacheUsage(entries: CacheEntry[]): CacheNamespaceUsage[] {
+  const usage = new Map<string, CacheNamespaceUsage>();
+
+  for (const entry of entries) {
+    let namespaceUsage = usage.get(entry.namespace);
+    if (!namespaceUsage) {
+      namespaceUsage = { namespace: entry.namespace, files: 0, size: 0 };
+      usage.set(entry.namespace, namespaceUsage);
+    }
+    namespaceUsage.files++;
+    namespaceUsage.size += entry.size;
+    namespaceUsage.oldest = Math.min(
+      namespaceUsage.oldest ?? entry.lastUsed,
+      entry.lastUsed,
+    );
+    namespaceUsage.newest = Math.max(
+      namespaceUsage.newest ?? entry.lastUsed,
+      entry.lastUsed,
+    );
+  }
+
+  return Array.from(usage.values()).sort((a, b) =>
+    a.namespace.localeCompare(b.namespace),
+  );
+}
+
+/**
+ * Picks the files a prune removes: the ones older than maxAge, then the
+ * least recently used ones until the rest fits into maxSize
+ */
+export function selectPrunableEntries(
+  entries: CacheEntry[],
+  policy: CachePrunePolicy,
+  now: number = Date.now(),
+): CacheEntry[] {
+  const byLastUse = [...entries].sort((a, b) => a.lastUsed - b.lastUsed);
+  const prunable: CacheEntry[] = [];
+  let size = entries.reduce((total, entry) => total + entry.size, 0);
+
+  for (const entry of byLastUse) {
+    const expired =
+      policy.maxAge !== undefined && now - entry.lastUsed > policy.maxAge;
+    const overBudget = policy.maxSize !== undefined && size > policy.maxSize;
+    if (!expired && !overBudget) {
+      break;
+    }
+    prunable.push(entry);
+    size -= entry.size;
+  }
+
+  return prunable;
+}
+
+/**
+ * Removes cache files, a removed file is rendered again when needed
+ * @returns Bytes freed
+ */
+export function removeCacheEntries(entries: CacheEntry[]): number {
+  let freed = 0;
+  for (const entry of entries) {
+    unlinkSync(entry.path);
+    freed += entry.size;
+  }
+  return freed;
+}
+
+/**
+ * Removes the whole cache of the project, or the given namespaces of it
+ * @returns Bytes freed
+ * @throws Error if a namespace is not a plain directory name
+ */
+export function clearCache(projectDir: string, namespaces?: string[]): number {
+  const invalid = namespaces?.find((namespace) => !/^[\w-]+$/.test(namespace));
+  if (invalid !== undefined) {
+    throw new Error(`Invalid cache namespace "${invalid}"`);
+  }
+
+  const entries = listCacheEntries(projectDir, namespaces);
+  const freed = entries.reduce((total, entry) => total + entry.size, 0);
+
+  const cacheDir = getCacheDir(projectDir);
+  if (!namespaces) {
+    rmSync(cacheDir, { recursive: true, force: true });
+    return freed;
+  }
+
+  for (const namespace of namespaces) {
+    if (namespace === 'other') {
+      removeCacheEntries(
+        entries.filter((entry) => entry.namespace === 'other'),
+      );
+    } else {
+      rmSync(resolve(cacheDir, namespace), { recursive: true, force: true });
+    }
+  }
+  return freed;
+}
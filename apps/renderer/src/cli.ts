@@ -9,6 +9,13 @@ import { registerAddAssetsCommand } from './cli/commands/add-assets.js';
 import { registerUploadCommand } from './cli/commands/upload.js';
 import { registerAuthCommand } from './cli/commands/auth.js';
 import { registerFiltersCommand } from './cli/commands/filters.js';
+import { registerExplainCommand } from './cli/commands/explain.js';
+import { registerBenchCommand } from './cli/commands/bench.js';
+import { registerLockCommand } from './cli/commands/lock.js';
+import { registerDoctorCommand } from './cli/commands/doctor.js';
+import { registerTestCommand } from './cli/commands/test.js';
+import { registerEditCommands } from './cli/commands/edit.js';
+import { registerCacheCommand } from './cli/commands/cache.js';
 
 // Read version from package.json
 // In built code, this file is at dist/cli.js, package.json is at ../package.json
@@ -67,5 +74,12 @@ registerAddAssetsCommand(program, handleError);
 registerUploadCommand(program, handleError);
 registerAuthCommand(program, handleError);
 registerFiltersCommand(program);
+registerExplainCommand(program, handleError);
+registerBenchCommand(program, handleError);
+registerLockCommand(program, handleError);
+registerDoctorCommand(program, handleError);
+registerTestCommand(program, handleError);
+registerEditCommands(program, handleError);
+registerCacheCommand(program, handleError);
 
 program.parse(process.argv);
@@ -0,0 +1,203 @@
+import { Command } from 'commander';
+import { resolve, dirname, basename } from 'path';
+import {
+  existsSync,
+  mkdtempSync,
+  readFileSync,
+  realpathSync,
+  rmSync,
+} from 'fs';
+import { tmpdir } from 'os';
+import {
+  BENCH_PROFILES,
+  BenchReport,
+  BenchResult,
+  benchProject,
+  compareBenchReports,
+  findExampleProjects,
+} from '../../bench.js';
+import { writeFile } from '../../lib/file.js';
+
+function formatMs(ms: number | undefined): string {
+  return ms === undefined ? '-' : `${(ms / 1000).toFixed(2)}s`;
+}
+
+function formatChange(change: number): string {
+  return `${change >= 0 ? '+' : ''}${(change * 100).toFixed(1)}%`;
+}
+
+function printResult(result: BenchResult): void {
+  const label = `${result.project}/${result.output || '?'} [${result.profile}]`;
+
+  if (result.error || !result.median) {
+    console.log(`❌ ${label}: ${result.error ?? 'no runs'}`);
+    return;
+  }
+
+  const { stages, total, encodeFps } = result.median;
+  console.log(`✅ ${label}`);
+  console.log(
+    `   total ${formatMs(total)} | encode ${encodeFps.toFixed(1)} fps | cache hit rate ${
+      result.cacheHitRate === undefined
+        ? '-'
+        : `${Math.round(result.cacheHitRate * 100)}%`
+    }`,
+  );
+  console.log(
+    `   parse ${formatMs(stages.parse)} | containers ${formatMs(stages.containers)} | apps ${formatMs(stages.apps)} | build ${formatMs(stages.build)} | encode ${formatMs(stages.encode)}`,
+  );
+}
+
+export function registerBenchCommand(
+  program: Command,
+  handleError: (error: any, operation: string) => void,
+): void {
+  program
+    .command('bench')
+    .description(
+      'Render the bundled example projects and report encode fps, cache efficiency and stage timings',
+    )
+    .option(
+      '-e, --examples <dir>',
+      'Directory with example projects (bundled examples if not specified)',
+    )
+    .option(
+      '--project <name...>',
+      'Only benchmark these example projects (directory names)',
+    )
+    .option(
+      '--profile <name...>',
+      `Profiles to render with (${BENCH_PROFILES.map((profile) => profile.name).join(', ')})`,
+      ['preview'],
+    )
+    .option('-r, --runs <n>', 'Runs per project and profile', '3')
+    .option('--json <path>', 'Write the results to a JSON file')
+    .option(
+      '--compare <path>',
+      'Compare with a previous JSON result and flag regressions',
+    )
+    .option(
+      '--threshold <percent>',
+      'Slowdown that counts as a regression when comparing',
+      '10',
+    )
+    .action(async (options) => {
+      try {
+        // When built, cli.js is in apps/renderer/dist/, and examples are at ../../../examples
+        const examplesDir = options.examples
+          ? resolve(process.cwd(), options.examples)
+          : resolve(
+              dirname(realpathSync(process.argv[1])),
+              '../../../examples',
+            );
+
+        let projectDirs = findExampleProjects(examplesDir);
+        if (options.project) {
+          projectDirs = projectDirs.filter((dir) =>
+            options.project.includes(basename(dir)),
+          );
+        }
+        if (projectDirs.length === 0) {
+          console.error(`Error: No example projects found in ${examplesDir}`);
+          process.exit(1);
+        }
+
+        const profiles = (options.profile as string[]).map((name) => {
+          const profile = BENCH_PROFILES.find((entry) => entry.name === name);
+          if (!profile) {
+            console.error(
+              `Error: Unknown profile "${name}". Available: ${BENCH_PROFILES.map((entry) => entry.name).join(', ')}`,
+            );
+            process.exit(1);
+          }
+          return profile;
+        });
+
+        const runs = parseInt(options.runs, 10);
+        if (isNaN(runs) || runs < 1) {
+          console.error('Error: --runs must be a positive number');
+          process.exit(1);
+        }
+
+        console.log(`📂 Examples: ${examplesDir}`);
+        console.log(
+          `⏱️  ${projectDirs.length} project(s) × ${profiles.length} profile(s) × ${runs} run(s)\n`,
+        );
+
+        const tmpDir = mkdtempSync(resolve(tmpdir(), 'staticstripes-bench-'));
+        const report: BenchReport = {
+          date: new Date().toISOString(),
+          node: process.version,
+          platform: `${process.platform} ${process.arch}`,
+          runs,
+          results: [],
+        };
+
+        try {
+          for (const projectDir of projectDirs) {
+            for (const profile of profiles) {
+              console.log(
+                `🎬 ${basename(projectDir)} [${profile.name}: ${profile.description}]`,
+              );
+              const result = await benchProject({
+                projectDir,
+                profile,
+                runs,
+                tmpDir,
+                onRun: (run, index) =>
+                  console.log(
+                    `   run ${index + 1}/${runs}: ${formatMs(run.total)}, ${run.encodeFps.toFixed(1)} fps`,
+                  ),
+              });
+              report.results.push(result);
+            }
+          }
+        } finally {
+          rmSync(tmpDir, { recursive: true, force: true });
+        }
+
+        console.log('\n=== Results (median) ===\n');
+        report.results.forEach(printResult);
+
+        if (options.json) {
+          const jsonPath = resolve(process.cwd(), options.json);
+          writeFile(jsonPath, Buffer.from(JSON.stringify(report, null, 2)));
+          console.log(`\n📄 Results written to ${jsonPath}`);
+        }
+
+        if (options.compare) {
+          const baselinePath = resolve(process.cwd(), options.compare);
+          if (!existsSync(baselinePath)) {
+            console.error(`Error: Baseline not found at ${baselinePath}`);
+            process.exit(1);
+          }
+          const baseline: BenchReport = JSON.parse(
+            readFileSync(baselinePath, 'utf-8'),
+          );
+          const threshold = parseFloat(options.threshold) / 100;
+          const comparisons = compareBenchReports(report, baseline, threshold);
+
+          console.log(`\n=== Compared to ${baseline.date} ===\n`);
+          for (const comparison of comparisons) {
+            console.log(
+              `${comparison.regression ? '⚠️ ' : '✅'} ${comparison.key}: total ${formatChange(comparison.totalChange)}, encode fps ${formatChange(comparison.encodeFpsChange)}`,
+            );
+          }
+
+          if (comparisons.some((comparison) => comparison.regression)) {
+            console.log(
+              `\n❌ Regressions above ${options.threshold}% detected`,
+            );
+            process.exit(1);
+          }
+        }
+
+        if (report.results.some((result) => result.error)) {
+          process.exit(1);
+        }
+      } catch (error) {
+        handleError(error, 'Bench');
+        process.exit(1);
+      }
+    });
+}
@@ -0,0 +1,171 @@
+import { Command } from 'commander';
+import { resolve } from 'path';
+import {
+  CachePrunePolicy,
+  clearCache,
+  formatByteSize,
+  getCacheDir,
+  getCacheUsage,
+  listCacheEntries,
+  parseAge,
+  parseByteSize,
+  removeCacheEntries,
+  selectPrunableEntries,
+} from '../../cache.js';
+import { loadWorkspaceConfig } from '../../workspace-config.js';
+
+function formatAge(ms: number): string {
+  const hours = ms / (60 * 60 * 1000);
+  return hours >= 48 ? `${Math.floor(hours / 24)}d` : `${Math.floor(hours)}h`;
+}
+
+/**
+ * Prune policy of the --max-size/--max-age options, falling back to the
+ * "cache" section of the workspace config
+ */
+function resolvePrunePolicy(
+  options: { maxSize?: string; maxAge?: string },
+  projectPath: string,
+): CachePrunePolicy {
+  const policy: CachePrunePolicy = {
+    ...loadWorkspaceConfig(projectPath).cache,
+  };
+
+  if (options.maxSize !== undefined) {
+    policy.maxSize = parseByteSize(options.maxSize);
+    if (policy.maxSize === undefined) {
+      throw new Error(
+        `Invalid --max-size "${options.maxSize}", expected e.g. "500M" or "20G"`,
+      );
+    }
+  }
+  if (options.maxAge !== undefined) {
+    policy.maxAge = parseAge(options.maxAge);
+    if (policy.maxAge === undefined) {
+      throw new Error(
+        `Invalid --max-age "${options.maxAge}", expected e.g. "12h" or "30d"`,
+      );
+    }
+  }
+
+  return policy;
+}
+
+export function registerCacheCommand(
+  program: Command,
+  handleError: (error: any, operation: string) => void,
+): void {
+  const cache = program
+    .command('cache')
+    .description(
+      'Inspect and clean up the render cache (containers, apps, conformed footage, fonts)',
+    );
+
+  cache
+    .command('stats')
+    .description('Show size, file count and last use per cache namespace')
+    .option('-p, --project <path>', 'Path to project directory', '.')
+    .action((options) => {
+      try {
+        const projectPath = resolve(process.cwd(), options.project);
+        const usage = getCacheUsage(listCacheEntries(projectPath));
+
+        console.log(`📁 Cache: ${getCacheDir(projectPath)}\n`);
+        if (usage.length === 0) {
+          console.log('💡 The cache is empty');
+          return;
+        }
+
+        const now = Date.now();
+        for (const namespace of usage) {
+          console.log(
+            `   ${namespace.namespace.padEnd(12)} ${String(namespace.files).padStart(6)} file(s) ${formatByteSize(namespace.size).padStart(10)}   last used ${formatAge(now - namespace.newest!)} ago, oldest ${formatAge(now - namespace.oldest!)} ago`,
+          );
+        }
+
+        const files = usage.reduce((total, { files }) => total + files, 0);
+        const size = usage.reduce((total, { size }) => total + size, 0);
+        console.log(`\n   Total: ${files} file(s), ${formatByteSize(size)}`);
+      } catch (error) {
+        handleError(error, 'Cache stats');
+        process.exit(1);
+      }
+    });
+
+  cache
+    .command('prune')
+    .description(
+      'Remove cache files not used for --max-age, then the least recently used ones above --max-size',
+    )
+    .option('-p, --project <path>', 'Path to project directory', '.')
+    .option('--max-size <size>', 'Size budget of the cache, e.g. "20G"')
+    .option(
+      '--max-age <age>',
+      'Remove files not used for this long, e.g. "30d"',
+    )
+    .option(
+      '-n, --namespace <name...>',
+      'Only prune these namespaces (e.g. containers conform)',
+    )
+    .option('--dry-run', 'Only list the files that would be removed')
+    .action((options) => {
+      try {
+        const projectPath = resolve(process.cwd(), options.project);
+        const policy = resolvePrunePolicy(options, projectPath);
+        if (policy.maxSize === undefined && policy.maxAge === undefined) {
+          console.error(
+            'Error: Set --max-size and/or --max-age, or "cache" in staticstripes.json',
+          );
+          process.exit(1);
+        }
+
+        const entries = listCacheEntries(projectPath, options.namespace);
+        const prunable = selectPrunableEntries(entries, policy);
+        if (prunable.length === 0) {
+          console.log('✅ Nothing to prune');
+          return;
+        }
+
+        const size = prunable.reduce((total, entry) => total + entry.size, 0);
+        if (options.dryRun) {
+          prunable.forEach((entry) => console.log(`🗑️  ${entry.path}`));
+          console.log(
+            `\n💡 Would remove ${prunable.length} file(s), ${formatByteSize(size)}`,
+          );
+          return;
+        }
+
+        const freed = removeCacheEntries(prunable);
+        console.log(
+          `✅ Removed ${prunable.length} of ${entries.length} file(s), freed ${formatByteSize(freed)}`,
+        );
+      } catch (error) {
+        handleError(error, 'Cache prune');
+        process.exit(1);
+      }
+    });
+
+  cache
+    .command('clear')
+    .description('Remove the whole cache, or some namespaces of it')
+    .option('-p, --project <path>', 'Path to project directory', '.')
+    .option(
+      '-n, --namespace <name...>',
+      'Only clear these namespaces (e.g. containers apps)',
+    )
+    .action((options) => {
+      try {
+        const projectPath = resolve(process.cwd(), options.project);
+        const freed = clearCache(projectPath, options.namespace);
+        console.log(
+          `✅ Cleared ${options.namespace ? options.namespace.join(', ') : 'the cache'}, freed ${formatByteSize(freed)}`,
+        );
+        console.log(
+          '💡 Removed files are rendered again on the next generate',
+        );
+      } catch (error) {
+        handleError(error, 'Cache clear');
+        process.exit(1);
+      }
+    });
+}
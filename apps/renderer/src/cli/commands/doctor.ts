@@ -0,0 +1,129 @@
+import { Command } from 'commander';
+import { resolve } from 'path';
+import { existsSync, unlinkSync } from 'fs';
+import { HTMLParser } from '../../html-parser.js';
+import { HTMLProjectParser } from '../../html-project-parser.js';
+import {
+  DoctorCheck,
+  checkNode,
+  checkTool,
+  checkGpuEncoders,
+  checkBrowser,
+  checkDiskSpace,
+  checkFonts,
+  checkDeclaredFonts,
+  checkCache,
+  checkOutputPaths,
+  findBrokenCacheFiles,
+  summarizeChecks,
+} from '../../doctor.js';
+import {
+  loadWorkspaceConfig,
+  resolveParseMode,
+} from '../../workspace-config.js';
+
+const STATUS_ICONS = {
+  pass: '✅',
+  warn: '⚠️ ',
+  fail: '❌',
+};
+
+function printCheck(check: DoctorCheck): void {
+  console.log(`${STATUS_ICONS[check.status]} ${check.name}: ${check.message}`);
+  if (check.hint && check.status !== 'pass') {
+    console.log(`   💡 ${check.hint}`);
+  }
+}
+
+export function registerDoctorCommand(
+  program: Command,
+  handleError: (error: any, operation: string) => void,
+): void {
+  program
+    .command('doctor')
+    .description(
+      'Check the environment: FFmpeg, browser, fonts, disk space, cache, GPU encoders and output paths',
+    )
+    .option('-p, --project <path>', 'Path to project directory', '.')
+    .option('--fix', 'Remove broken cache files')
+    .action(async (options) => {
+      try {
+        const projectPath = resolve(process.cwd(), options.project);
+        const projectFilePath = resolve(projectPath, 'project.html');
+        const checks: DoctorCheck[] = [];
+        const run = async (check: DoctorCheck | Promise<DoctorCheck>) => {
+          const result = await check;
+          checks.push(result);
+          printCheck(result);
+        };
+
+        console.log('\n=== Environment ===\n');
+        await run(checkNode());
+        await run(checkTool('ffmpeg'));
+        await run(checkTool('ffprobe'));
+        await run(checkGpuEncoders());
+        await run(checkBrowser());
+        await run(checkDiskSpace(projectPath));
+
+        console.log('\n=== Project ===\n');
+        if (!existsSync(projectFilePath)) {
+          console.log(
+            `💡 No project.html in ${projectPath}, project checks skipped`,
+          );
+        } else {
+          console.log(`📁 Project: ${projectPath}\n`);
+
+          try {
+            const parser = new HTMLProjectParser(
+              await new HTMLParser().parseFile(projectFilePath),
+              projectFilePath,
+              {
+                mode: resolveParseMode(
+                  undefined,
+                  loadWorkspaceConfig(projectPath),
+                ),
+              },
+            );
+            const project = await parser.parse();
+            await run({
+              name: 'Project file',
+              status: 'pass',
+              message: `${project.getAssetManager().getAssets().length} asset(s), ${project.getOutputs().size} output(s)`,
+            });
+            await run(checkFonts(project.getCssText(), project.getFonts()));
+            await run(checkDeclaredFonts(project.getFonts(), projectPath));
+            await run(checkOutputPaths(project));
+          } catch (error) {
+            await run({
+              name: 'Project file',
+              status: 'fail',
+              message: error instanceof Error ? error.message : String(error),
+            });
+          }
+
+          if (options.fix) {
+            const broken = findBrokenCacheFiles(projectPath);
+            broken.forEach((path) => {
+              unlinkSync(path);
+              console.log(`🗑️  Removed broken cache file: ${path}`);
+            });
+          }
+          await run(checkCache(projectPath));
+        }
+
+        const summary = summarizeChecks(checks);
+
+        console.log('\n=== Summary ===\n');
+        console.log(
+          `✅ ${summary.pass} passed, ⚠️  ${summary.warn} warning(s), ❌ ${summary.fail} failed\n`,
+        );
+
+        if (summary.fail > 0) {
+          process.exit(1);
+        }
+      } catch (error) {
+        handleError(error, 'Doctor');
+        process.exit(1);
+      }
+    });
+}
@@ -0,0 +1,196 @@
+import { Command } from 'commander';
+import { basename, resolve } from 'path';
+import { existsSync, readFileSync, writeFileSync } from 'fs';
+import {
+  ProjectEditor,
+  applyEditCommand,
+  parsePosition,
+  splitEditCommand,
+} from '../../project-editor.js';
+import {
+  EDIT_HISTORY_DIR,
+  restoreLatestSnapshot,
+  saveSnapshot,
+} from '../../edit-history.js';
+
+function getProjectFilePath(project: string): {
+  projectPath: string;
+  projectFilePath: string;
+} {
+  const projectPath = resolve(process.cwd(), project);
+  const projectFilePath = resolve(projectPath, 'project.html');
+
+  if (!existsSync(projectFilePath)) {
+    console.error(`Error: project.html not found in ${projectPath}`);
+    process.exit(1);
+  }
+
+  return { projectPath, projectFilePath };
+}
+
+/**
+ * Runs edits against project.html. The file is only written if all edits
+ * succeed, after a snapshot of the previous version is saved for "undo".
+ */
+function editProject(
+  projectOption: string,
+  operation: string,
+  edit: (editor: ProjectEditor) => string[],
+): void {
+  const { projectPath, projectFilePath } = getProjectFilePath(projectOption);
+  const source = readFileSync(projectFilePath, 'utf-8');
+
+  const editor = new ProjectEditor(source);
+  const changes = edit(editor);
+
+  if (editor.getSource() === source) {
+    console.log('💡 No changes, project.html is already up to date');
+    return;
+  }
+
+  saveSnapshot(projectPath, projectFilePath, operation);
+  writeFileSync(projectFilePath, editor.getSource(), 'utf-8');
+
+  console.log(`📄 Updated ${projectFilePath}`);
+  changes.forEach((change) => console.log(`   ✏️  ${change}`));
+  console.log('\n💡 Run "staticstripes undo" to revert');
+}
+
+export function registerEditCommands(
+  program: Command,
+  handleError: (error: any, operation: string) => void,
+): void {
+  program
+    .command('set-style')
+    .description(
+      'Set a CSS property in a <style> rule of project.html (the rule is created if missing)',
+    )
+    .argument('<selector>', 'Rule selector, e.g. ".intro"')
+    .argument('<property>', 'CSS property, e.g. "width" or "-duration"')
+    .argument('<value...>', 'Property value, e.g. "50%"')
+    .option('-p, --project <path>', 'Path to project directory', '.')
+    .action((selector: string, property: string, value: string[], options) => {
+      try {
+        editProject(options.project, 'set-style', (editor) => [
+          editor.setStyle(selector, property, value.join(' ')),
+        ]);
+      } catch (error) {
+        handleError(error, 'Set style');
+        process.exit(1);
+      }
+    });
+
+  program
+    .command('add-fragment')
+    .description('Add a fragment to a sequence of project.html')
+    .argument('<sequence>', 'Sequence id or number (1-based)')
+    .option('-p, --project <path>', 'Path to project directory', '.')
+    .option('--id <id>', 'Fragment id')
+    .option('--class <names>', 'Fragment class names')
+    .option('--asset <name>', 'Asset name (data-asset)')
+    .option('--style <css>', 'Inline style')
+    .option(
+      '--at <position>',
+      'Position among the fragments (1-based), appended if not set',
+    )
+    .action((sequence: string, options) => {
+      try {
+        editProject(options.project, 'add-fragment', (editor) => [
+          editor.addFragment(sequence, {
+            id: options.id,
+            className: options.class,
+            asset: options.asset,
+            style: options.style,
+            position:
+              options.at !== undefined ? parsePosition(options.at) : undefined,
+          }),
+        ]);
+      } catch (error) {
+        handleError(error, 'Add fragment');
+        process.exit(1);
+      }
+    });
+
+  program
+    .command('retime')
+    .description('Set the duration of a fragment or gap in project.html')
+    .argument(
+      '<fragment>',
+      'Fragment reference: "main:2" (sequence:number), "main:intro" or a fragment id',
+    )
+    .argument('<duration>', 'Duration, e.g. "4s" or "1500ms"')
+    .option('-p, --project <path>', 'Path to project directory', '.')
+    .action((fragment: string, duration: string, options) => {
+      try {
+        editProject(options.project, 'retime', (editor) => [
+          editor.retime(fragment, duration),
+        ]);
+      } catch (error) {
+        handleError(error, 'Retime');
+        process.exit(1);
+      }
+    });
+
+  program
+    .command('batch')
+    .description(
+      'Apply edit commands from a file, one per line (all or nothing, undone at once)',
+    )
+    .argument('<file>', 'File with set-style/add-fragment/retime lines')
+    .option('-p, --project <path>', 'Path to project directory', '.')
+    .action((file: string, options) => {
+      try {
+        const filePath = resolve(process.cwd(), file);
+        if (!existsSync(filePath)) {
+          console.error(`Error: ${filePath} not found`);
+          process.exit(1);
+        }
+
+        const lines = readFileSync(filePath, 'utf-8').split('\n');
+
+        editProject(options.project, 'batch', (editor) =>
+          lines.flatMap((line, index) => {
+            const args = splitEditCommand(line.trim());
+            if (args.length === 0 || args[0].startsWith('#')) {
+              return [];
+            }
+            try {
+              return [applyEditCommand(editor, args)];
+            } catch (error) {
+              throw new Error(
+                `${basename(filePath)}:${index + 1}: ${error instanceof Error ? error.message : String(error)}`,
+              );
+            }
+          }),
+        );
+      } catch (error) {
+        handleError(error, 'Batch edit');
+        process.exit(1);
+      }
+    });
+
+  program
+    .command('undo')
+    .description(
+      `Revert project.html to its state before the last edit command (history in ${EDIT_HISTORY_DIR})`,
+    )
+    .option('-p, --project <path>', 'Path to project directory', '.')
+    .action((options) => {
+      try {
+        const { projectPath, projectFilePath } = getProjectFilePath(
+          options.project,
+        );
+
+        const snapshot = restoreLatestSnapshot(projectPath, projectFilePath);
+        if (!snapshot) {
+          console.log('💡 Nothing to undo');
+          return;
+        }
+
+        console.log(`✅ Restored project.html from ${basename(snapshot)}`);
+      } catch (error) {
+        handleError(error, 'Undo');
+        process.exit(1);
+      }
+    });
+}
@@ -0,0 +1,128 @@
+import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
+import type { Element } from 'domhandler';
+import { HTMLParser } from '../../html-parser';
+import { HTMLProjectParser } from '../../html-project-parser';
+import { makeFFmpegCommandForAssets } from '../../ffmpeg';
+import { Project } from '../../project';
+import { Asset } from '../../type';
+import { resolveFragmentRef } from './explain';
+
+describe('explain', () => {
+  const makeAsset = (name: string, duration: number): Asset => ({
+    name,
+    path: `/tmp/test/${name}.mp4`,
+    type: 'video',
+    duration,
+    width: 1920,
+    height: 1080,
+    rotation: 0,
+    hasVideo: true,
+    hasAudio: true,
+  });
+
+  let project: Project;
+
+  beforeEach(async () => {
+    // Assets by name, instead of probing files with ffprobe
+    const assets: Record<string, Asset> = {
+      a: makeAsset('a', 5000),
+      b: makeAsset('b', 4000),
+    };
+    vi.spyOn(
+      HTMLProjectParser.prototype as any,
+      'extractAssetFromElement',
+    ).mockImplementation(async (element: unknown) => ({
+      ...assets[(element as Element).attribs['data-name']],
+    }));
+
+    const parsed = new HTMLParser().parse(`
+      <title>Test</title>
+      <assets>
+        <asset data-name="a" data-path="./a.mp4" />
+        <asset data-name="b" data-path="./b.mp4" />
+      </assets>
+      <outputs>
+        <output name="youtube" resolution="1920x1080" fps="30" />
+      </outputs>
+      <project>
+        <sequence id="main">
+          <fragment id="intro" data-asset="a" />
+          <fragment id="broll" data-asset="b" />
+        </sequence>
+        <sequence>
+          <fragment id="logo" data-asset="a" />
+        </sequence>
+      </project>
+    `);
+    project = await new HTMLProjectParser(
+      parsed,
+      '/tmp/test/project.html',
+    ).parse();
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it('should resolve sequence:number, sequence:id and bare ids', () => {
+    const sequences = project.getSequenceDefinitions();
+
+    expect(resolveFragmentRef(sequences, 'main:2')).toEqual({
+      sequenceIndex: 0,
+      fragmentIndex: 1,
+    });
+    expect(resolveFragmentRef(sequences, '1:broll')).toEqual({
+      sequenceIndex: 0,
+      fragmentIndex: 1,
+    });
+    expect(resolveFragmentRef(sequences, '2:1')).toEqual({
+      sequenceIndex: 1,
+      fragmentIndex: 0,
+    });
+    expect(resolveFragmentRef(sequences, 'logo')).toEqual({
+      sequenceIndex: 1,
+      fragmentIndex: 0,
+    });
+  });
+
+  it('should explain unknown sequences and fragments', () => {
+    const sequences = project.getSequenceDefinitions();
+
+    expect(() => resolveFragmentRef(sequences, 'outro:1')).toThrow(
+      'Sequence "outro" not found',
+    );
+    expect(() => resolveFragmentRef(sequences, '3:1')).toThrow(
+      'Sequence "3" not found',
+    );
+    expect(() => resolveFragmentRef(sequences, 'main:logo')).toThrow(
+      'Fragment "main:logo" not found',
+    );
+    expect(() => resolveFragmentRef(sequences, 'main:3')).toThrow(
+      'Fragment "main:3" not found',
+    );
+    // positions need an explicit sequence
+    expect(() => resolveFragmentRef(sequences, '2')).toThrow(
+      'Fragment "2" not found',
+    );
+  });
+
+  it('should build a standalone command with just the inputs of a fragment', async () => {
+    const { buf, assetManager } = await project.buildFragments(
+      'youtube',
+      0,
+      [1],
+    );
+    const filter = buf.render();
+    const command = makeFFmpegCommandForAssets(
+      assetManager,
+      project.getOutput('youtube')!,
+      filter,
+    );
+
+    expect(Array.from(assetManager.getAssetIndexMap())).toEqual([['b', 0]]);
+    expect(command.match(/-i "[^"]*"/g)).toEqual(['-i "/tmp/test/b.mp4"']);
+    expect(filter).toContain('[0:v]');
+    expect(filter).not.toContain('[1:v]');
+    expect(filter).toContain('[outv]');
+  });
+});
@@ -0,0 +1,223 @@
+import { Command } from 'commander';
+import { resolve, dirname, extname } from 'path';
+import { existsSync } from 'fs';
+import { HTMLParser } from '../../html-parser.js';
+import { HTMLProjectParser } from '../../html-project-parser.js';
+import {
+  makeFFmpegCommandForAssets,
+  getDefaultFFmpegArgs,
+} from '../../ffmpeg.js';
+import { SequenceDefinition } from '../../type.js';
+import {
+  loadWorkspaceConfig,
+  resolveParseMode,
+} from '../../workspace-config.js';
+
+/**
+ * Resolves a fragment reference into sequence and fragment indices (0-based)
+ * Formats:
+ *   "main:2"  - 2nd fragment of the sequence with id="main"
+ *   "1:2"     - 2nd fragment of the 1st sequence
+ *   "main:intro" / "intro" - fragment with id="intro"
+ */
+export function resolveFragmentRef(
+  sequences: SequenceDefinition[],
+  ref: string,
+): { sequenceIndex: number; fragmentIndex: number } {
+  const separator = ref.lastIndexOf(':');
+  const sequenceRef = separator >= 0 ? ref.slice(0, separator) : '';
+  const fragmentRef = separator >= 0 ? ref.slice(separator + 1) : ref;
+
+  let sequenceIndices = sequences.map((_sequence, index) => index);
+  if (sequenceRef) {
+    const byId = sequences.findIndex((sequence) => sequence.id === sequenceRef);
+    const byNumber = /^\d+$/.test(sequenceRef)
+      ? parseInt(sequenceRef, 10) - 1
+      : -1;
+    const sequenceIndex = byId >= 0 ? byId : byNumber;
+
+    if (sequenceIndex < 0 || sequenceIndex >= sequences.length) {
+      throw new Error(`Sequence "${sequenceRef}" not found`);
+    }
+    sequenceIndices = [sequenceIndex];
+  }
+
+  for (const sequenceIndex of sequenceIndices) {
+    const fragments = sequences[sequenceIndex].fragments;
+
+    const byId = fragments.findIndex((fragment) => fragment.id === fragmentRef);
+    if (byId >= 0) {
+      return { sequenceIndex, fragmentIndex: byId };
+    }
+
+    // Numbers are 1-based positions, only when the sequence is explicit
+    if (sequenceRef && /^\d+$/.test(fragmentRef)) {
+      const fragmentIndex = parseInt(fragmentRef, 10) - 1;
+      if (fragmentIndex >= 0 && fragmentIndex < fragments.length) {
+        return { sequenceIndex, fragmentIndex };
+      }
+    }
+  }
+
+  throw new Error(`Fragment "${ref}" not found`);
+}
+
+export function registerExplainCommand(
+  program: Command,
+  handleError: (error: any, operation: string) => void,
+): void {
+  program
+    .command('explain')
+    .description(
+      'Print the standalone FFmpeg command and filter graph of a fragment or transition',
+    )
+    .option('-p, --project <path>', 'Path to project directory', '.')
+    .option(
+      '-o, --output <name>',
+      'Output name to explain for (first output if not specified)',
+    )
+    .option(
+      '--fragment <ref>',
+      'Fragment to explain, e.g. "main:2" (sequence id or number : fragment number or id)',
+    )
+    .option(
+      '--transition <ref>',
+      'Transition from the fragment to the next one, e.g. "main:2"',
+    )
+    .option(
+      '--option <name>',
+      'FFmpeg option preset to use (from project.html <ffmpeg> section)',
+    )
+    .option(
+      '--strict',
+      'Fail on unknown elements/properties and missing attributes',
+    )
+    .option(
+      '--no-strict',
+      'Force permissive parsing (overrides the workspace config)',
+    )
+    .action(async (options) => {
+      try {
+        const ref: string | undefined = options.fragment || options.transition;
+        if (!ref || (options.fragment && options.transition)) {
+          console.error(
+            'Error: Specify either --fragment <ref> or --transition <ref>',
+          );
+          process.exit(1);
+        }
+
+        const projectPath = resolve(process.cwd(), options.project);
+        const projectFilePath = resolve(projectPath, 'project.html');
+
+        if (!existsSync(projectFilePath)) {
+          console.error(`Error: project.html not found in ${projectPath}`);
+          process.exit(1);
+        }
+
+        const parser = new HTMLProjectParser(
+          await new HTMLParser().parseFile(projectFilePath),
+          projectFilePath,
+          {
+            mode: resolveParseMode(
+              options.strict,
+              loadWorkspaceConfig(projectPath),
+            ),
+          },
+        );
+        const project = await parser.parse();
+
+        const outputName: string | undefined =
+          options.output || Array.from(project.getOutputs().keys())[0];
+        const output = outputName ? project.getOutput(outputName) : undefined;
+        if (!outputName || !output) {
+          console.error(
+            `Error: Output "${options.output}" not found in project.html`,
+          );
+          process.exit(1);
+        }
+
+        const sequences = project.getSequenceDefinitions();
+        const { sequenceIndex, fragmentIndex } = resolveFragmentRef(
+          sequences,
+          ref,
+        );
+        const fragmentIndices = options.transition
+          ? [fragmentIndex, fragmentIndex + 1]
+          : [fragmentIndex];
+
+        const fragments = sequences[sequenceIndex].fragments;
+        if (fragmentIndices.some((index) => index >= fragments.length)) {
+          console.error(
+            `Error: Fragment "${ref}" is the last one in its sequence, there is no transition after it`,
+          );
+          process.exit(1);
+        }
+
+        // Containers and apps are fragment assets, render them (cached)
+        if (!output.format) {
+          await project.renderContainers(outputName);
+          await project.renderApps(outputName);
+        }
+
+        const { buf, assetManager } = await project.buildFragments(
+          outputName,
+          sequenceIndex,
+          fragmentIndices,
+        );
+        const filter = buf.render();
+
+        let ffmpegArgs = getDefaultFFmpegArgs(output);
+        if (options.option) {
+          const ffmpegOption = project.getFfmpegOption(options.option);
+          if (!ffmpegOption) {
+            console.error(
+              `Error: FFmpeg option "${options.option}" not found in project.html`,
+            );
+            process.exit(1);
+          }
+          ffmpegArgs = ffmpegOption.args;
+        }
+
+        // Write next to the real output, never over it
+        const slug = ref.replace(/[^a-zA-Z0-9_-]+/g, '_');
+        const explainOutput = {
+          ...output,
+          path: resolve(
+            dirname(output.path),
+            `explain_${options.transition ? 'transition' : 'fragment'}_${slug}${extname(output.path)}`,
+          ),
+        };
+
+        const ffmpegCommand = makeFFmpegCommandForAssets(
+          assetManager,
+          explainOutput,
+          filter,
+          ffmpegArgs,
+        );
+
+        const sequenceLabel =
+          sequences[sequenceIndex].id || `${sequenceIndex + 1}`;
+        const fragmentLabels = fragmentIndices
+          .map((index) => {
+            const fragment = fragments[index];
+            return `${sequenceLabel}:${index + 1} id="${fragment.id}" (${fragment.gap ? 'gap' : fragment.assetName || 'no asset'})`;
+          })
+          .join(' → ');
+
+        console.log(
+          `\n🔎 ${options.transition ? 'Transition' : 'Fragment'}: ${fragmentLabels}`,
+        );
+        console.log(`📹 Output: ${outputName}\n`);
+
+        console.log('=== FFmpeg Command ===\n');
+        console.log(ffmpegCommand);
+
+        console.log('\n=== Filter Graph ===\n');
+        console.log(filter.split(';').join(';\n'));
+        console.log('\n====================\n');
+      } catch (error) {
+        handleError(error, 'Explain');
+        process.exit(1);
+      }
+    });
+}
@@ -1,16 +1,45 @@
 import { Command } from 'commander';
-import { resolve, dirname } from 'path';
-import { existsSync, mkdirSync } from 'fs';
+import { resolve, dirname, basename, extname } from 'path';
+import { existsSync, mkdirSync, writeFileSync } from 'fs';
 import { HTMLParser } from '../../html-parser.js';
 import { HTMLProjectParser } from '../../html-project-parser.js';
 import {
-  makeFFmpegCommand,
+  makeFFmpegCommandForAssets,
+  makeFanOutFFmpegCommand,
   runFFMpeg,
   checkFFmpegInstalled,
+  getDefaultFFmpegArgs,
+  TimeRange,
 } from '../../ffmpeg.js';
 import { getAssetDuration } from '../../ffprobe.js';
 import { cleanupStaleCache } from '../../container-renderer.js';
 import { formatDuration } from '../../time-utils.js';
+import {
+  loadWorkspaceConfig,
+  resolveParseMode,
+} from '../../workspace-config.js';
+import { writeCrashReport, RenderPlan } from '../../crash-report.js';
+import { LogCapture } from '../../lib/log-capture.js';
+import { loadAssetLock, verifyAssetLock } from '../../asset-lock.js';
+import { makeDescription } from '../../description.js';
+import { conformVariableFrameRateAssets } from '../../conform.js';
+import {
+  groupOutputsForFanOut,
+  getFanOutPrimary,
+} from '../../fan-out.js';
+
+/**
+ * Parses --between "from,to" into two marker names
+ */
+export function parseBetweenOption(value: string): [string, string] {
+  const names = value.split(',').map((name) => name.trim());
+  if (names.length !== 2 || !names[0] || !names[1]) {
+    throw new Error(
+      `Invalid --between "${value}", expected two marker names, e.g. "hook,cta"`,
+    );
+  }
+  return [names[0], names[1]];
+}
 
 export function registerGenerateCommand(
   program: Command,
@@ -33,26 +62,76 @@ export function registerGenerateCommand(
       '--app-build',
       'Force rebuild apps even if build output already exists',
     )
+    .option(
+      '--strict',
+      'Fail on unknown elements/properties and missing attributes',
+    )
+    .option(
+      '--no-strict',
+      'Force permissive parsing (overrides the workspace config)',
+    )
+    .option(
+      '--locked',
+      'Fail if assets differ from staticstripes.lock.json (see the lock command)',
+    )
+    .option(
+      '--no-description',
+      'Do not write the <output>.description.txt file (chapters and credits)',
+    )
+    .option(
+      '--no-fan-out',
+      'Render every output separately, even when outputs could share one composite',
+    )
+    .option(
+      '--no-conform',
+      'Use variable frame rate sources as they are, without converting them to constant frame rate',
+    )
+    .option(
+      '--between <from,to>',
+      'Only render the part between two <marker> names, into <output>.<from>-<to>.<ext>',
+    )
+    .option(
+      '--bundle-report',
+      'On failure, write a crash report bundle (.tar.gz) into the project directory',
+    )
     .action(async (options) => {
+      // Resolve project path
+      const projectPath = resolve(process.cwd(), options.project);
+      const projectFilePath = resolve(projectPath, 'project.html');
+
+      // Crash report data: what was about to be rendered and the console output
+      const plan: RenderPlan = {};
+      const logCapture = new LogCapture();
+      if (options.bundleReport) {
+        logCapture.start();
+      }
+
       try {
         // Check if FFmpeg is installed
         console.log('🔍 Checking for FFmpeg...');
         await checkFFmpegInstalled();
         console.log('✅ FFmpeg found\n');
 
-        // Resolve project path
-        const projectPath = resolve(process.cwd(), options.project);
-        const projectFilePath = resolve(projectPath, 'project.html');
-
         // Validate project.html exists
         if (!existsSync(projectFilePath)) {
-          console.error(`Error: project.html not found in ${projectPath}`);
-          process.exit(1);
+          throw new Error(`project.html not found in ${projectPath}`);
         }
 
         console.log(`📁 Project: ${projectPath}`);
         console.log(`📄 Loading: ${projectFilePath}\n`);
 
+        const between = options.between
+          ? parseBetweenOption(options.between)
+          : undefined;
+
+        // Parse mode: --strict/--no-strict, then workspace config
+        const workspaceConfig = loadWorkspaceConfig(projectPath);
+        const parseMode = resolveParseMode(options.strict, workspaceConfig);
+        plan.parseMode = parseMode;
+        if (parseMode === 'strict') {
+          console.log('🔒 Strict parse mode\n');
+        }
+
         // Step 1: Light parse to extract AI generation requirements
         const lightParser = new HTMLProjectParser(
           await new HTMLParser().parseFile(projectFilePath),
@@ -110,25 +189,46 @@ export function registerGenerateCommand(
         const initialParser = new HTMLProjectParser(
           await new HTMLParser().parseFile(projectFilePath),
           projectFilePath,
+          { mode: parseMode },
         );
         const initialProject = await initialParser.parse();
 
+        // Locked mode: assets must be exactly the ones in the lockfile
+        if (options.locked) {
+          const lock = loadAssetLock(projectPath);
+          if (!lock) {
+            throw new Error(
+              'No staticstripes.lock.json found, run "staticstripes lock" first',
+            );
+          }
+
+          console.log('🔒 Verifying assets against the lockfile...');
+          const problems = await verifyAssetLock(
+            projectPath,
+            initialProject.getAssetManager().getAssets(),
+            lock,
+          );
+          if (problems.length > 0) {
+            throw new Error(
+              `Assets differ from the lockfile:\n${problems.map((problem) => `  - ${problem}`).join('\n')}\n\nRun "staticstripes lock" if the changes are intended`,
+            );
+          }
+          console.log('✅ Assets match the lockfile\n');
+        }
+
         // Determine which outputs to render
         const allOutputs = Array.from(initialProject.getOutputs().keys());
         const outputsToRender = options.output ? [options.output] : allOutputs;
 
         if (outputsToRender.length === 0) {
-          console.error('Error: No outputs defined in project.html');
-          process.exit(1);
+          throw new Error('No outputs defined in project.html');
         }
 
         // Validate requested output exists
         if (options.output && !allOutputs.includes(options.output)) {
-          console.error(
-            `Error: Output "${options.output}" not found in project.html`,
+          throw new Error(
+            `Output "${options.output}" not found in project.html. Available outputs: ${allOutputs.join(', ')}`,
           );
-          console.error(`Available outputs: ${allOutputs.join(', ')}`);
-          process.exit(1);
         }
 
         // Log which outputs will be rendered
@@ -137,41 +237,123 @@ export function registerGenerateCommand(
         // Create a shared cache key store for all outputs
         const activeCacheKeys = new Set<string>();
 
-        // Step 4: Render each output
-        for (const outputName of outputsToRender) {
+        // Outputs differing only by resolution/bitrate share one composite,
+        // with containers or apps only if their text scales alike
+        const outputGroups: string[][] = options.fanOut
+          ? groupOutputsForFanOut(
+              outputsToRender.map((name) => initialProject.getOutput(name)!),
+              initialProject.hasContainersOrApps(),
+            ).map((group) => group.map((output) => output.name))
+          : outputsToRender.map((name) => [name]);
+
+        // Step 4: Render each output (or group of outputs)
+        for (const outputNames of outputGroups) {
           // Re-parse the project for each output to ensure clean state
           const parser = new HTMLProjectParser(
             await new HTMLParser().parseFile(projectFilePath),
             projectFilePath,
+            { mode: parseMode },
           );
           const project = await parser.parse();
 
           console.log(`\n${'='.repeat(60)}`);
-          console.log(`📹 Rendering: ${outputName}`);
+          console.log(`📹 Rendering: ${outputNames.join(', ')}`);
           console.log(`${'='.repeat(60)}\n`);
 
-          // Get output info and ensure output directory exists
-          const output = project.getOutput(outputName);
-          if (!output) {
-            throw new Error(`Output "${outputName}" not found`);
+          // Get output info and ensure output directories exist
+          const outputs = outputNames.map((name) => {
+            const output = project.getOutput(name);
+            if (!output) {
+              throw new Error(`Output "${name}" not found`);
+            }
+            return output;
+          });
+
+          // The composite is built at the largest output of the group
+          const output = getFanOutPrimary(outputs);
+          const outputName = output.name;
+          const isFanOut = outputs.length > 1;
+          if (isFanOut) {
+            console.log(
+              `🔀 Fan-out: one composite at ${output.resolution.width}x${output.resolution.height} (${outputName}), encoded to ${outputs.length} outputs\n`,
+            );
+          }
+
+          plan.outputName = outputNames.join(', ');
+          plan.output = output;
+          plan.assets = project.getAssetManager().getAssets();
+          plan.sequences = undefined;
+          plan.filter = undefined;
+          plan.ffmpegArgs = undefined;
+          plan.ffmpegCommand = undefined;
+
+          for (const { path } of outputs) {
+            const outputDir = dirname(path);
+            if (!existsSync(outputDir)) {
+              console.log(`📂 Creating output directory: ${outputDir}`);
+              mkdirSync(outputDir, { recursive: true });
+            }
           }
 
-          const outputDir = dirname(output.path);
-          if (!existsSync(outputDir)) {
-            console.log(`📂 Creating output directory: ${outputDir}`);
-            mkdirSync(outputDir, { recursive: true });
+          // Variable frame rate sources (phone footage) drift when
+          // concatenated, they are converted to the output fps first
+          if (!output.format && options.conform) {
+            const conformed = await conformVariableFrameRateAssets(
+              project.getAssetManager().getAssets(),
+              projectPath,
+              output.fps,
+            );
+            if (conformed.length > 0) {
+              console.log(
+                `🎞️  Conformed to ${output.fps}fps: ${conformed.join(', ')}\n`,
+              );
+            }
           }
 
-          // Render containers and apps for this output (accumulate cache keys)
-          await project.renderContainers(outputName, activeCacheKeys);
-          await project.renderApps(outputName, activeCacheKeys, options.appBuild);
+          if (output.format) {
+            // Audio-only output: no video compositing, containers or apps
+            console.log(`🎧 Audio-only output (${output.format})`);
+          } else {
+            // Render containers and apps for this output (accumulate cache keys)
+            await project.renderContainers(outputName, activeCacheKeys);
+            await project.renderApps(
+              outputName,
+              activeCacheKeys,
+              options.appBuild,
+            );
+          }
 
           // Print project statistics
           project.printStats();
 
           // Build filter graph
-          const filterBuf = await project.build(outputName);
+          const filterBuf = isFanOut
+            ? await project.buildFanOut(outputNames)
+            : await project.build(outputName);
           const filter = filterBuf.render();
+          plan.sequences = project.getDebugInfo();
+          plan.filter = filter;
+
+          // Region render: same graph, encoded between two markers,
+          // into separate files so the full outputs are kept
+          let range: TimeRange | undefined;
+          if (between) {
+            range = project.getMarkerRange(...between);
+            console.log(
+              `📍 Region ${between[0]} → ${between[1]}: ${formatDuration(range.start)} - ${formatDuration(range.end)}`,
+            );
+          }
+          const targets = outputs.map((target) =>
+            between
+              ? {
+                  ...target,
+                  path: resolve(
+                    dirname(target.path),
+                    `${basename(target.path, extname(target.path))}.${between[0]}-${between[1]}${extname(target.path)}`,
+                  ),
+                }
+              : target,
+          );
 
           // Print debug information before ffmpeg if debug mode is enabled
           if (isDebugMode()) {
@@ -180,8 +362,7 @@ export function registerGenerateCommand(
 
           // Determine FFmpeg arguments to use
           let ffmpegArgs: string;
-          const defaultArgs =
-            '-c:v libx264 -pix_fmt yuv420p -preset medium -c:a aac -b:a 192k';
+          const defaultArgs = getDefaultFFmpegArgs(output);
 
           if (options.option) {
             // User specified an option name, look it up in project
@@ -190,19 +371,9 @@ export function registerGenerateCommand(
               const availableOptions = Array.from(
                 project.getFfmpegOptions().keys(),
               );
-              console.error(
-                `Error: FFmpeg option "${options.option}" not found in project.html`,
+              throw new Error(
+                `FFmpeg option "${options.option}" not found in project.html. ${availableOptions.length > 0 ? `Available options: ${availableOptions.join(', ')}` : 'No FFmpeg options defined in project.html <ffmpeg> section'}`,
               );
-              if (availableOptions.length > 0) {
-                console.error(
-                  `Available options: ${availableOptions.join(', ')}`,
-                );
-              } else {
-                console.error(
-                  'No FFmpeg options defined in project.html <ffmpeg> section',
-                );
-              }
-              process.exit(1);
             }
             ffmpegArgs = ffmpegOption.args;
             console.log(`⚡ Using FFmpeg option: ${options.option}`);
@@ -213,12 +384,28 @@ export function registerGenerateCommand(
           }
 
           // Generate FFmpeg command
-          const ffmpegCommand = makeFFmpegCommand(
-            project,
-            filter,
-            outputName,
-            ffmpegArgs,
-          );
+          plan.ffmpegArgs = ffmpegArgs;
+          const ffmpegCommand = isFanOut
+            ? makeFanOutFFmpegCommand(
+                project.getAssetManager(),
+                targets.map((target) => ({
+                  output: target,
+                  // default arguments depend on the output (e.g. alpha codecs)
+                  ffmpegArgs: options.option
+                    ? ffmpegArgs
+                    : getDefaultFFmpegArgs(target),
+                })),
+                filter,
+                range,
+              )
+            : makeFFmpegCommandForAssets(
+                project.getAssetManager(),
+                targets[0],
+                filter,
+                ffmpegArgs,
+                range,
+              );
+          plan.ffmpegCommand = ffmpegCommand;
 
           if (isDebugMode()) {
             console.log('\n=== FFmpeg Command ===\n');
@@ -237,12 +424,37 @@ export function registerGenerateCommand(
           const renderEndTime = Date.now();
           const renderingDuration = renderEndTime - renderStartTime;
 
-          const resultPath = output.path;
-          console.log(`\n✅ Output file: ${resultPath}`);
+          for (const { path: resultPath, format } of targets) {
+            console.log(`\n✅ Output file: ${resultPath}`);
 
-          const videoDuration = await getAssetDuration(resultPath);
-          console.log(`📹 Video duration: ${formatDuration(videoDuration)}`);
+            const videoDuration = await getAssetDuration(resultPath);
+            console.log(
+              `${format ? '🎧 Audio' : '📹 Video'} duration: ${formatDuration(videoDuration)}`,
+            );
+          }
           console.log(`⏱️  Rendering duration: ${formatDuration(renderingDuration)}`);
+
+          // Ready-to-paste description: chapters (data-timecode) and credits
+          // (chapter times are those of the full video, not of a region)
+          if (options.description && !between) {
+            const description = makeDescription(project);
+            if (
+              description.chapters.length > 0 ||
+              description.credits.length > 0
+            ) {
+              for (const { path } of outputs) {
+                const descriptionPath = resolve(
+                  dirname(path),
+                  `${basename(path, extname(path))}.description.txt`,
+                );
+                writeFileSync(descriptionPath, description.text);
+                console.log(`📝 Description: ${descriptionPath}`);
+              }
+              description.warnings.forEach((warning) =>
+                console.warn(`⚠️  ${warning}`),
+              );
+            }
+          }
         }
 
         // Clean up stale cache entries after all outputs are rendered
@@ -254,6 +466,27 @@ export function registerGenerateCommand(
         console.log('\n🎉 All outputs rendered successfully!\n');
       } catch (error) {
         handleError(error, 'Video generation');
+
+        if (options.bundleReport) {
+          logCapture.stop();
+          try {
+            const reportPath = await writeCrashReport({
+              projectPath,
+              plan,
+              logs: logCapture.getText(),
+              error,
+            });
+            console.error(`📦 Crash report bundle: ${reportPath}`);
+            console.error(
+              '💡 Nothing was uploaded. Review the bundle for private data before attaching it to a bug report.\n',
+            );
+          } catch (reportError) {
+            console.error(
+              `❌ Could not write the crash report bundle: ${reportError instanceof Error ? reportError.message : String(reportError)}\n`,
+            );
+          }
+        }
+
         process.exit(1);
       }
     });
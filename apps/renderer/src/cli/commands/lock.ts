@@ -0,0 +1,90 @@
+import { Command } from 'commander';
+import { resolve } from 'path';
+import { existsSync } from 'fs';
+import { HTMLParser } from '../../html-parser.js';
+import { HTMLProjectParser } from '../../html-project-parser.js';
+import {
+  diffAssetLocks,
+  loadAssetLock,
+  makeAssetLock,
+  verifyAssetLock,
+  writeAssetLock,
+} from '../../asset-lock.js';
+
+export function registerLockCommand(
+  program: Command,
+  handleError: (error: any, operation: string) => void,
+): void {
+  program
+    .command('lock')
+    .description(
+      'Record checksums and probed metadata of all assets in staticstripes.lock.json',
+    )
+    .option('-p, --project <path>', 'Path to project directory', '.')
+    .option(
+      '--check',
+      'Only compare the assets with the existing lockfile, do not write it',
+    )
+    .action(async (options) => {
+      try {
+        const projectPath = resolve(process.cwd(), options.project);
+        const projectFilePath = resolve(projectPath, 'project.html');
+
+        if (!existsSync(projectFilePath)) {
+          console.error(`Error: project.html not found in ${projectPath}`);
+          process.exit(1);
+        }
+
+        console.log(`📁 Project: ${projectPath}`);
+
+        const parser = new HTMLProjectParser(
+          await new HTMLParser().parseFile(projectFilePath),
+          projectFilePath,
+        );
+        const project = await parser.parse();
+        const assets = project.getAssetManager().getAssets();
+
+        const previousLock = loadAssetLock(projectPath);
+
+        if (options.check) {
+          if (!previousLock) {
+            console.error(
+              'Error: No staticstripes.lock.json found, run "staticstripes lock" first',
+            );
+            process.exit(1);
+          }
+
+          console.log('🔒 Verifying assets...\n');
+          const problems = await verifyAssetLock(
+            projectPath,
+            assets,
+            previousLock,
+          );
+          if (problems.length > 0) {
+            problems.forEach((problem) => console.log(`❌ ${problem}`));
+            process.exit(1);
+          }
+
+          console.log('✅ All assets match the lockfile');
+          return;
+        }
+
+        console.log('🔒 Hashing assets...\n');
+        const lock = await makeAssetLock(projectPath, assets);
+
+        if (previousLock) {
+          diffAssetLocks(previousLock, lock).forEach((change) =>
+            console.log(`🔄 ${change}`),
+          );
+        }
+
+        const lockPath = writeAssetLock(projectPath, lock);
+        console.log(
+          `\n✅ Locked ${Object.keys(lock.assets).length} asset(s) in ${lockPath}`,
+        );
+      } catch (error) {
+        handleError(error, 'Lock');
+        process.exit(1);
+      }
+    });
+}
@@ -0,0 +1,118 @@
+import { Command } from 'commander';
+import { resolve } from 'path';
+import { existsSync, mkdtempSync, rmSync } from 'fs';
+import { tmpdir } from 'os';
+import { HTMLParser } from '../../html-parser.js';
+import { HTMLProjectParser } from '../../html-project-parser.js';
+import { checkFFmpegInstalled } from '../../ffmpeg.js';
+import { runAssertions, AssertionResult } from '../../assertions.js';
+import {
+  loadWorkspaceConfig,
+  resolveParseMode,
+} from '../../workspace-config.js';
+import { parseBetweenOption } from './generate.js';
+
+export function registerTestCommand(
+  program: Command,
+  handleError: (error: any, operation: string) => void,
+): void {
+  program
+    .command('test')
+    .description(
+      'Evaluate the <assert> elements of a project (text, pixel colors, duration)',
+    )
+    .option('-p, --project <path>', 'Path to project directory', '.')
+    .option('-o, --output <name>', 'Only check assertions of this output')
+    .option(
+      '--between <from,to>',
+      'Only check assertions at a time between two <marker> names',
+    )
+    .option(
+      '--strict',
+      'Fail on unknown elements/properties and missing attributes',
+    )
+    .option(
+      '--no-strict',
+      'Force permissive parsing (overrides the workspace config)',
+    )
+    .action(async (options) => {
+      try {
+        const between = options.between
+          ? parseBetweenOption(options.between)
+          : undefined;
+        const projectPath = resolve(process.cwd(), options.project);
+        const projectFilePath = resolve(projectPath, 'project.html');
+
+        if (!existsSync(projectFilePath)) {
+          console.error(`Error: project.html not found in ${projectPath}`);
+          process.exit(1);
+        }
+
+        await checkFFmpegInstalled();
+
+        console.log(`📁 Project: ${projectPath}\n`);
+
+        const parser = new HTMLProjectParser(
+          await new HTMLParser().parseFile(projectFilePath),
+          projectFilePath,
+          {
+            mode: resolveParseMode(
+              options.strict,
+              loadWorkspaceConfig(projectPath),
+            ),
+          },
+        );
+        const project = await parser.parse();
+
+        if (options.output && !project.getOutput(options.output)) {
+          console.error(
+            `Error: Output "${options.output}" not found in project.html`,
+          );
+          process.exit(1);
+        }
+
+        if (project.getAssertions().length === 0) {
+          console.log('💡 No <assert> elements found in project.html');
+          return;
+        }
+
+        const tmpDir = mkdtempSync(resolve(tmpdir(), 'staticstripes-test-'));
+        let results: AssertionResult[];
+        try {
+          results = await runAssertions(
+            project,
+            tmpDir,
+            options.output,
+            between,
+          );
+        } finally {
+          rmSync(tmpDir, { recursive: true, force: true });
+        }
+
+        if (between) {
+          console.log(`\n📍 Region ${between[0]} → ${between[1]}`);
+        }
+        console.log('\n=== Assertions ===\n');
+        for (const { assertion, output, failures } of results) {
+          if (failures.length === 0) {
+            console.log(`✅ ${assertion.name} [${output}]`);
+          } else {
+            console.log(`❌ ${assertion.name} [${output}]`);
+            failures.forEach((failure) => console.log(`   ${failure}`));
+          }
+        }
+
+        const failed = results.filter((result) => result.failures.length > 0);
+        console.log(
+          `\n${failed.length === 0 ? '🎉' : '❌'} ${results.length - failed.length} passed, ${failed.length} failed\n`,
+        );
+
+        if (failed.length > 0) {
+          process.exit(1);
+        }
+      } catch (error) {
+        handleError(error, 'Test');
+        process.exit(1);
+      }
+    });
+}
@@ -0,0 +1,102 @@
+import { describe, it, expect } from 'vitest';
+import { mkdirSync, mkdtempSync, rmSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import { resolve } from 'path';
+import {
+  parseFrameRate,
+  isVariableFrameRate,
+  makeConformFFmpegCommand,
+  conformVariableFrameRateAssets,
+  getConformCacheKey,
+} from './conform';
+import { Asset } from './type';
+
+describe('Variable frame rate conform', () => {
+  it('should parse ffprobe frame rates', () => {
+    expect(parseFrameRate('30/1')).toBe(30);
+    expect(parseFrameRate('30000/1001')).toBeCloseTo(29.97, 2);
+    expect(parseFrameRate('25')).toBe(25);
+    expect(parseFrameRate('0/0')).toBe(0);
+  });
+
+  it('should detect variable frame rate streams', () => {
+    // phone footage: nominal 30fps, fewer frames on average
+    expect(isVariableFrameRate('30/1', '1739000/60833')).toBe(true);
+    expect(isVariableFrameRate('90000/1', '30/1')).toBe(true);
+
+    expect(isVariableFrameRate('30000/1001', '30000/1001')).toBe(false);
+    expect(isVariableFrameRate('25/1', '0/0')).toBe(false);
+  });
+
+  it('should convert to constant frame rate keeping the audio in sync', () => {
+    const command = makeConformFFmpegCommand(
+      '/tmp/in.mp4',
+      '/tmp/out.mkv',
+      30,
+      true,
+    );
+
+    expect(command).toContain('-vsync cfr');
+    expect(command).toContain('-r 30');
+    expect(command).toContain('aresample=async=1');
+    expect(command).toContain('-map 0:a:0');
+
+    expect(
+      makeConformFFmpegCommand('/tmp/in.mp4', '/tmp/out.mkv', 30, false),
+    ).not.toContain('-map 0:a:0');
+  });
+
+  it('should keep the alpha channel of transparent sources', () => {
+    const command = makeConformFFmpegCommand(
+      '/tmp/in.webm',
+      '/tmp/out.mov',
+      30,
+      false,
+      { decoder: 'libvpx-vp9' },
+    );
+
+    expect(command).toContain('-c:v libvpx-vp9 -i "/tmp/in.webm"');
+    expect(command).toContain(
+      '-c:v prores_ks -profile:v 4444 -pix_fmt yuva444p10le',
+    );
+    expect(command).not.toContain('libx264');
+  });
+
+  it('should point alpha assets at a conformed file with alpha', async () => {
+    const projectDir = mkdtempSync(resolve(tmpdir(), 'staticstripes-conform-'));
+    try {
+      const path = resolve(projectDir, 'recording.webm');
+      writeFileSync(path, 'webm');
+      // already conformed, so no ffmpeg run is needed
+      const cacheDir = resolve(projectDir, 'cache', 'conform');
+      mkdirSync(cacheDir, { recursive: true });
+      const conformedPath = resolve(
+        cacheDir,
+        `${getConformCacheKey(path, 30)}.mov`,
+      );
+      writeFileSync(conformedPath, 'mov');
+
+      const asset: Asset = {
+        name: 'recording',
+        path,
+        type: 'video',
+        duration: 1000,
+        width: 1920,
+        height: 1080,
+        rotation: 0,
+        hasVideo: true,
+        hasAudio: false,
+        variableFrameRate: true,
+        hasAlpha: true,
+        alphaDecoder: 'libvpx-vp9',
+      };
+      await conformVariableFrameRateAssets([asset], projectDir, 30);
+
+      expect(asset.path).toBe(conformedPath);
+      expect(asset.hasAlpha).toBe(true);
+      expect(asset.alphaDecoder).toBeUndefined();
+    } finally {
+      rmSync(projectDir, { recursive: true, force: true });
+    }
+  });
+});
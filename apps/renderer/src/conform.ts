@@ -0,0 +1,167 @@
+import { createHash } from 'crypto';
+import { existsSync, renameSync, statSync } from 'fs';
+import { mkdir } from 'fs/promises';
+import { resolve } from 'path';
+import { Asset } from './type';
+import { runFFMpeg } from './ffmpeg';
+import { recordCacheHit, recordCacheMiss } from './cache-stats';
+
+// Relative difference between the nominal and the average frame rate above
+// which a stream is treated as variable frame rate
+const VFR_TOLERANCE = 0.005;
+
+/**
+ * Parses an ffprobe frame rate ("30000/1001", "30/1" or "29.97"),
+ * returns 0 for unknown rates ("0/0")
+ */
+export function parseFrameRate(value: string): number {
+  const [numerator, denominator] = value.trim().split('/');
+  const rate =
+    denominator === undefined
+      ? parseFloat(numerator)
+      : parseFloat(numerator) / parseFloat(denominator);
+  return Number.isFinite(rate) && rate > 0 ? rate : 0;
+}
+
+/**
+ * A stream is variable frame rate when its average frame rate differs from
+ * the nominal one (r_frame_rate), as with most phone footage
+ */
+export function isVariableFrameRate(
+  rFrameRate: string,
+  avgFrameRate: string,
+): boolean {
+  const nominal = parseFrameRate(rFrameRate);
+  const average = parseFrameRate(avgFrameRate);
+  if (nominal === 0 || average === 0) {
+    return false;
+  }
+  return Math.abs(nominal - average) / nominal > VFR_TOLERANCE;
+}
+
+/**
+ * Cache key of a conformed file: the source file (path, size, modification
+ * time) and the target frame rate
+ */
+export function getConformCacheKey(path: string, fps: number): string {
+  const { size, mtimeMs } = statSync(path);
+  return createHash('sha256')
+    .update(JSON.stringify({ path, size, mtimeMs, fps }))
+    .digest('hex')
+    .substring(0, 16);
+}
+
+/**
+ * Generates the ffmpeg command that converts a source to constant frame rate:
+ * frames are duplicated/dropped to the target fps, and the audio is
+ * resampled to its timestamps (aresample async), so it stays in sync.
+ * Sources with alpha are decoded with their alpha decoder and converted to
+ * ProRes 4444, which keeps the alpha channel (the output must be a .mov).
+ */
+export function makeConformFFmpegCommand(
+  inputPath: string,
+  outputPath: string,
+  fps: number,
+  hasAudio: boolean,
+  alpha?: { decoder?: string },
+): string {
+  return [
+    'ffmpeg',
+    '-y',
+    // frames stay as stored, the render applies the asset's rotation
+    '-noautorotate',
+    ...(alpha?.decoder ? [`-c:v ${alpha.decoder}`] : []),
+    `-i "${inputPath}"`,
+    '-map 0:v:0',
+    ...(hasAudio ? ['-map 0:a:0'] : []),
+    '-vsync cfr',
+    `-r ${fps}`,
+    // visually lossless intermediate, it's encoded again by the render
+    alpha
+      ? '-c:v prores_ks -profile:v 4444 -pix_fmt yuva444p10le'
+      : '-c:v libx264 -preset veryfast -crf 14 -pix_fmt yuv420p',
+    ...(hasAudio
+      ? ['-af aresample=async=1:first_pts=0', '-c:a pcm_s16le']
+      : []),
+    `"${outputPath}"`,
+  ].join(' ');
+}
+
+/**
+ * Converts variable frame rate video assets to constant frame rate at the
+ * output fps (cached in cache/conform), and points the assets (and their
+ * sub-clips) at the conformed files. Without it, VFR sources drift against
+ * the rest of the timeline when concatenated.
+ * @returns Names of the conformed assets
+ */
+export async function conformVariableFrameRateAssets(
+  assets: Asset[],
+  projectDir: string,
+  fps: number,
+): Promise<string[]> {
+  const sources = new Set(
+    assets
+      .filter((asset) => asset.variableFrameRate && asset.type === 'video')
+      .map((asset) => asset.path),
+  );
+  if (sources.size === 0) {
+    return [];
+  }
+
+  const cacheDir = resolve(projectDir, 'cache', 'conform');
+  await mkdir(cacheDir, { recursive: true });
+
+  const conformedPaths = new Map<string, string>();
+  for (const path of sources) {
+    const source = assets.find((asset) => asset.path === path);
+    const alpha = source?.hasAlpha
+      ? { decoder: source.alphaDecoder }
+      : undefined;
+    const conformedPath = resolve(
+      cacheDir,
+      `${getConformCacheKey(path, fps)}.${alpha ? 'mov' : 'mkv'}`,
+    );
+
+    if (existsSync(conformedPath)) {
+      recordCacheHit('conform');
+      console.log(`🎞️  Using conformed ${path} (${fps}fps) from cache`);
+    } else {
+      recordCacheMiss('conform');
+      console.log(
+        `🎞️  Conforming variable frame rate ${path} to ${fps}fps...`,
+      );
+      const hasAudio = assets.some(
+        (asset) => asset.path === path && asset.hasAudio,
+      );
+      // Written under a temporary name, so an interrupted conversion
+      // is never picked up from the cache
+      const partialPath = conformedPath.replace(
+        /\.(mkv|mov)$/,
+        '.partial.$1',
+      );
+      await runFFMpeg(
+        makeConformFFmpegCommand(path, partialPath, fps, hasAudio, alpha),
+        { silent: true },
+      );
+      renameSync(partialPath, conformedPath);
+    }
+
+    conformedPaths.set(path, conformedPath);
+  }
+
+  const conformed: string[] = [];
+  for (const asset of assets) {
+    const conformedPath = conformedPaths.get(asset.path);
+    if (!conformedPath) {
+      continue;
+    }
+
+    asset.path = conformedPath;
+    asset.variableFrameRate = false;
+    // the conformed file is ProRes, the native decoder keeps its alpha
+    delete asset.alphaDecoder;
+    conformed.push(asset.name);
+  }
+
+  return conformed;
+}
@@ -3,7 +3,8 @@ import { writeFile, mkdir, readdir, unlink } from 'fs/promises';
 import { resolve } from 'path';
 import { existsSync } from 'fs';
 import { createHash } from 'crypto';
-import { Container } from './type';
+import { Container, Output } from './type';
+import { recordCacheHit, recordCacheMiss } from './cache-stats';
 
 export interface RenderContainerOptions {
   container: Container;
@@ -12,6 +13,7 @@ export interface RenderContainerOptions {
   height: number;
   projectDir: string;
   outputName: string;
+  rootFontSize?: number;
 }
 
 export interface ContainerRenderResult {
@@ -19,18 +21,44 @@ export interface ContainerRenderResult {
   screenshotPath: string;
 }
 
+const DEFAULT_ROOT_FONT_SIZE = 16;
+
 /**
- * Generates a hash from container content, CSS, and output name
+ * Font size of the container page root (1rem) for an output. With a base
+ * size, rem grows with the output like vw/vh do, so a composition designed
+ * for 1920x1080 looks the same in a 1280x720 proxy and a 3840x2160 master.
+ * An output with another aspect ratio takes the smaller of the two scales,
+ * so the text still fits.
+ */
+export function getRootFontSize(output: Output): number {
+  if (!output.baseSize) {
+    return DEFAULT_ROOT_FONT_SIZE;
+  }
+
+  const scale = Math.min(
+    output.resolution.width / output.baseSize.width,
+    output.resolution.height / output.baseSize.height,
+  );
+  return Math.round(DEFAULT_ROOT_FONT_SIZE * scale * 100) / 100;
+}
+
+/**
+ * Generates a hash from container content, CSS, output name and root font size
  */
 function generateCacheKey(
   containerHtml: string,
   cssText: string,
   outputName: string,
+  rootFontSize: number,
 ): string {
   const hash = createHash('sha256');
   hash.update(containerHtml);
   hash.update(cssText);
   hash.update(outputName);
+  // the default size keeps the keys of projects without a base size
+  if (rootFontSize !== DEFAULT_ROOT_FONT_SIZE) {
+    hash.update(String(rootFontSize));
+  }
   return hash.digest('hex').substring(0, 16);
 }
 
@@ -40,7 +68,15 @@ function generateCacheKey(
 export async function renderContainer(
   options: RenderContainerOptions,
 ): Promise<ContainerRenderResult> {
-  const { container, cssText, width, height, projectDir, outputName } = options;
+  const {
+    container,
+    cssText,
+    width,
+    height,
+    projectDir,
+    outputName,
+    rootFontSize = DEFAULT_ROOT_FONT_SIZE,
+  } = options;
 
   // Create cache directory
   const cacheDir = resolve(projectDir, 'cache', 'containers');
@@ -49,11 +85,17 @@ export async function renderContainer(
   }
 
   // Generate cache key from content hash
-  const cacheKey = generateCacheKey(container.htmlContent, cssText, outputName);
+  const cacheKey = generateCacheKey(
+    container.htmlContent,
+    cssText,
+    outputName,
+    rootFontSize,
+  );
   const screenshotPath = resolve(cacheDir, `${cacheKey}.png`);
 
   // Check if cached version exists
   if (existsSync(screenshotPath)) {
+    recordCacheHit('containers');
     console.log(
       `Using cached container "${container.id}" (hash: ${cacheKey}) from ${screenshotPath}`,
     );
@@ -62,6 +104,7 @@ export async function renderContainer(
       screenshotPath,
     };
   }
+  recordCacheMiss('containers');
 
   // Build complete HTML document
   const html = `
@@ -75,12 +118,15 @@ export async function renderContainer(
       padding: 0;
       box-sizing: border-box;
     }
+    html {
+      font-size: ${rootFontSize}px;
+    }
     body {
       width: ${width}px;
       height: ${height}px;
       overflow: hidden;
       background: transparent;
-      font-size: 16px;
+      font-size: ${rootFontSize}px;
     }
     ${cssText}
   </style>
@@ -102,6 +148,12 @@ export async function renderContainer(
     await page.setViewport({ width, height });
     await page.setContent(html, { waitUntil: 'networkidle0' });
 
+    // Fonts of <fonts> (font-display: block) must be loaded before the screenshot
+    await page.evaluate(async () => {
+      // @ts-expect-error - This runs in browser context
+      await document.fonts.ready;
+    });
+
     // Take screenshot with transparent background
     const screenshot = await page.screenshot({
       type: 'png',
@@ -173,6 +225,7 @@ export async function renderContainers(
   projectDir: string,
   outputName: string,
   activeCacheKeys?: Set<string>,
+  rootFontSize: number = DEFAULT_ROOT_FONT_SIZE,
 ): Promise<ContainerRenderResult[]> {
   const results: ContainerRenderResult[] = [];
 
@@ -182,6 +235,7 @@ export async function renderContainers(
       container.htmlContent,
       cssText,
       outputName,
+      rootFontSize,
     );
 
     // Add to provided Set or create a local one (for backwards compatibility)
@@ -196,6 +250,7 @@ export async function renderContainers(
       height,
       projectDir,
       outputName,
+      rootFontSize,
     });
     results.push(result);
   }
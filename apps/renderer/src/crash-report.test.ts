@@ -0,0 +1,70 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { execFileSync } from 'child_process';
+import {
+  mkdirSync,
+  mkdtempSync,
+  readFileSync,
+  readdirSync,
+  rmSync,
+  writeFileSync,
+} from 'fs';
+import { tmpdir } from 'os';
+import { basename, resolve } from 'path';
+import { writeCrashReport } from './crash-report';
+import { FFmpegError } from './ffmpeg';
+
+describe('Crash report', () => {
+  let projectDir: string;
+
+  beforeEach(() => {
+    projectDir = mkdtempSync(resolve(tmpdir(), 'staticstripes-report-'));
+    writeFileSync(resolve(projectDir, 'project.html'), '<project></project>');
+  });
+
+  afterEach(() => {
+    rmSync(projectDir, { recursive: true, force: true });
+  });
+
+  it('should bundle the project, plan and FFmpeg output', async () => {
+    const bundlePath = await writeCrashReport({
+      projectPath: projectDir,
+      plan: {
+        outputName: 'youtube',
+        filter: '[0:v]null[v0];[v0]null[outv]',
+        ffmpegCommand: 'ffmpeg -i in.mp4 out.mp4',
+      },
+      logs: 'Rendering youtube\n',
+      error: new FFmpegError('FFmpeg exited with code 1', 'bad filter', 1),
+    });
+
+    expect(bundlePath).toMatch(/staticstripes-report-.*\.tar\.gz$/);
+
+    // the bundle must be readable by the system tar
+    const extractDir = resolve(projectDir, 'extracted');
+    mkdirSync(extractDir);
+    execFileSync('tar', ['-xzf', bundlePath, '-C', extractDir]);
+
+    const [folder] = readdirSync(extractDir);
+    expect(folder).toBe(basename(bundlePath, '.tar.gz'));
+    const read = (name: string) =>
+      readFileSync(resolve(extractDir, folder, name), 'utf-8');
+
+    expect(readdirSync(resolve(extractDir, folder)).sort()).toEqual([
+      'README.txt',
+      'error.txt',
+      'ffmpeg-command.txt',
+      'ffmpeg-stderr.txt',
+      'filter-graph.txt',
+      'log.txt',
+      'plan.json',
+      'project.html',
+      'versions.json',
+    ]);
+    expect(read('project.html')).toBe('<project></project>');
+    expect(JSON.parse(read('plan.json')).outputName).toBe('youtube');
+    expect(read('filter-graph.txt')).toBe('[0:v]null[v0];\n[v0]null[outv]\n');
+    expect(read('ffmpeg-stderr.txt')).toBe('bad filter');
+    expect(read('error.txt')).toContain('FFmpeg exited with code 1');
+    expect(read('log.txt')).toBe('Rendering youtube\n');
+  });
+});
@@ -0,0 +1,126 @@
+import { execFile } from 'child_process';
+import { promisify } from 'util';
+import { existsSync, readFileSync } from 'fs';
+import { resolve } from 'path';
+import { Asset, Output, ParseMode, SequenceDebugInfo } from './type';
+import { FFmpegError } from './ffmpeg';
+import { createTarGz, TarEntry } from './lib/tar';
+import { writeFile } from './lib/file';
+import { findWorkspaceConfigPath } from './workspace-config';
+
+const execFileAsync = promisify(execFile);
+
+/**
+ * What the renderer was about to do when it failed, filled in step by step
+ */
+export type RenderPlan = {
+  parseMode?: ParseMode;
+  outputName?: string;
+  output?: Output;
+  assets?: Asset[];
+  sequences?: SequenceDebugInfo[];
+  ffmpegArgs?: string;
+  filter?: string;
+  ffmpegCommand?: string;
+};
+
+/**
+ * Returns the first line of "<tool> -version", or why it couldn't be run
+ */
+async function getToolVersion(tool: string): Promise<string> {
+  try {
+    const { stdout } = await execFileAsync(tool, ['-version']);
+    return stdout.split('\n')[0].trim();
+  } catch (error) {
+    return `unavailable (${error instanceof Error ? error.message : String(error)})`;
+  }
+}
+
+function getPackageVersion(): string {
+  try {
+    // In built code, this file is at dist/crash-report.js
+    const packageJsonPath = resolve(__dirname, '../package.json');
+    return JSON.parse(readFileSync(packageJsonPath, 'utf-8')).version;
+  } catch {
+    return 'unknown';
+  }
+}
+
+/**
+ * Writes a crash report bundle (.tar.gz) into the project directory:
+ * project file, workspace config, resolved plan, tool versions, console log
+ * and the stderr of the failing FFmpeg run.
+ * The bundle stays on disk, nothing is sent anywhere.
+ * @returns Path of the written bundle
+ */
+export async function writeCrashReport(options: {
+  projectPath: string;
+  plan: RenderPlan;
+  logs: string;
+  error: unknown;
+}): Promise<string> {
+  const { projectPath, plan, logs, error } = options;
+
+  const timestamp = new Date().toISOString().replace(/[:.]/g, '-');
+  const folder = `staticstripes-report-${timestamp}`;
+  const entries: TarEntry[] = [];
+  const add = (name: string, content: Buffer | string) =>
+    entries.push({ name: `${folder}/${name}`, content });
+
+  add(
+    'README.txt',
+    [
+      'StaticStripes crash report',
+      '',
+      'This bundle was created locally and has not been uploaded anywhere.',
+      'It contains your project file, asset paths and the console output,',
+      'please review it for private data before attaching it to a bug report.',
+      '',
+    ].join('\n'),
+  );
+
+  const projectFilePath = resolve(projectPath, 'project.html');
+  if (existsSync(projectFilePath)) {
+    add('project.html', readFileSync(projectFilePath));
+  }
+
+  const workspaceConfigPath = findWorkspaceConfigPath(projectPath);
+  if (workspaceConfigPath) {
+    add('staticstripes.json', readFileSync(workspaceConfigPath));
+  }
+
+  add('plan.json', JSON.stringify(plan, null, 2));
+
+  if (plan.filter) {
+    add('filter-graph.txt', plan.filter.split(';').join(';\n') + '\n');
+  }
+  if (plan.ffmpegCommand) {
+    add('ffmpeg-command.txt', plan.ffmpegCommand + '\n');
+  }
+
+  const versions = {
+    staticstripes: getPackageVersion(),
+    node: process.version,
+    platform: `${process.platform} ${process.arch}`,
+    ffmpeg: await getToolVersion('ffmpeg'),
+    ffprobe: await getToolVersion('ffprobe'),
+  };
+  add('versions.json', JSON.stringify(versions, null, 2));
+
+  add(
+    'error.txt',
+    error instanceof Error
+      ? `${error.message}\n\n${error.stack ?? ''}\n`
+      : `${String(error)}\n`,
+  );
+  if (error instanceof FFmpegError) {
+    add('ffmpeg-stderr.txt', error.stderr);
+  }
+
+  add('log.txt', logs);
+
+  const bundlePath = resolve(projectPath, `${folder}.tar.gz`);
+  writeFile(bundlePath, createTarGz(entries));
+
+  return bundlePath;
+}
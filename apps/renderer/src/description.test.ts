@@ -0,0 +1,34 @@
+import { describe, it, expect } from 'vitest';
+import { validateChapters } from './description';
+import { formatChapterTime } from './time-utils';
+
+describe('Video description', () => {
+  it('should format chapter times the way YouTube parses them', () => {
+    expect(formatChapterTime(0)).toBe('0:00');
+    expect(formatChapterTime(65500)).toBe('1:05');
+    expect(formatChapterTime(3723000)).toBe('1:02:03');
+  });
+
+  it('should accept chapters that follow the YouTube rules', () => {
+    const chapters = [
+      { time: 0, label: 'Intro' },
+      { time: 15000, label: 'Beach' },
+      { time: 40000, label: 'Sunset' },
+    ];
+
+    expect(validateChapters(chapters, 60000)).toEqual([]);
+  });
+
+  it('should warn about chapters YouTube would ignore', () => {
+    const chapters = [
+      { time: 5000, label: 'Beach' },
+      { time: 20000, label: 'Sunset' },
+    ];
+
+    expect(validateChapters(chapters, 25000)).toEqual([
+      'The first chapter "Beach" starts at 0:05, YouTube requires one at 0:00',
+      'Only 2 chapter(s), YouTube requires at least 3',
+      'Chapter "Sunset" at 0:20 is shorter than 10s',
+    ]);
+  });
+});
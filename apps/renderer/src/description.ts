@@ -0,0 +1,125 @@
+import { Project } from './project';
+import { Chapter } from './type';
+import { formatChapterTime } from './time-utils';
+
+// YouTube only turns timestamps into chapters if these rules are met
+const MIN_CHAPTERS = 3;
+const MIN_CHAPTER_DURATION = 10000; // ms
+
+export type Credit = {
+  author: string;
+  assets: string[]; // names of the used assets by this author
+};
+
+/**
+ * Collects authors of the assets that are actually used by enabled fragments
+ */
+export function getCredits(project: Project): Credit[] {
+  const credits = new Map<string, string[]>();
+
+  for (const sequence of project.getSequenceDefinitions()) {
+    for (const fragment of sequence.fragments) {
+      if (!fragment.enabled) {
+        continue;
+      }
+
+      const asset = project.getAssetManager().getAssetByName(fragment.assetName);
+      if (!asset?.author) {
+        continue;
+      }
+
+      const assets = credits.get(asset.author) ?? [];
+      if (!assets.includes(asset.name)) {
+        assets.push(asset.name);
+      }
+      credits.set(asset.author, assets);
+    }
+  }
+
+  return Array.from(credits, ([author, assets]) => ({ author, assets }));
+}
+
+/**
+ * Checks chapters against the YouTube chapter rules
+ * @param totalDuration - Duration of the video in ms, the last chapter ends there
+ * @returns Warnings, empty if YouTube will show the chapters
+ */
+export function validateChapters(
+  chapters: Chapter[],
+  totalDuration: number,
+): string[] {
+  const warnings: string[] = [];
+  if (chapters.length === 0) {
+    return warnings;
+  }
+
+  if (Math.round(chapters[0].time) >= 1000) {
+    warnings.push(
+      `The first chapter "${chapters[0].label}" starts at ${formatChapterTime(chapters[0].time)}, YouTube requires one at 0:00`,
+    );
+  }
+
+  if (chapters.length < MIN_CHAPTERS) {
+    warnings.push(
+      `Only ${chapters.length} chapter(s), YouTube requires at least ${MIN_CHAPTERS}`,
+    );
+  }
+
+  chapters.forEach((chapter, index) => {
+    const end =
+      index + 1 < chapters.length ? chapters[index + 1].time : totalDuration;
+    if (end - chapter.time < MIN_CHAPTER_DURATION) {
+      warnings.push(
+        `Chapter "${chapter.label}" at ${formatChapterTime(chapter.time)} is shorter than ${MIN_CHAPTER_DURATION / 1000}s`,
+      );
+    }
+  });
+
+  return warnings;
+}
+
+/**
+ * Makes a ready-to-paste video description: title, chapters and credits
+ * Note: This must be called after build(), chapter times come from the timeline
+ */
+export function makeDescription(project: Project): {
+  text: string;
+  chapters: Chapter[];
+  credits: Credit[];
+  warnings: string[];
+} {
+  const chapters = project.getChapters();
+  const credits = getCredits(project);
+  const totalDuration = Math.max(
+    0,
+    ...project.getDebugInfo().map((sequence) => sequence.totalDuration),
+  );
+
+  const lines: string[] = [];
+  if (project.getTitle()) {
+    lines.push(project.getTitle(), '');
+  }
+
+  if (chapters.length > 0) {
+    lines.push('Chapters:');
+    for (const { time, label } of chapters) {
+      lines.push(`${formatChapterTime(time)} ${label}`);
+    }
+    lines.push('');
+  }
+
+  if (credits.length > 0) {
+    lines.push('Credits:');
+    for (const { author } of credits) {
+      lines.push(`- ${author}`);
+    }
+    lines.push('');
+  }
+
+  return {
+    text: lines.join('\n'),
+    chapters,
+    credits,
+    warnings: validateChapters(chapters, totalDuration),
+  };
+}
@@ -0,0 +1,115 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { mkdirSync, mkdtempSync, rmSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import { dirname, resolve } from 'path';
+import {
+  DoctorCheck,
+  checkCache,
+  checkDeclaredFonts,
+  findBrokenCacheFiles,
+  getCssFontFamilies,
+  summarizeChecks,
+} from './doctor';
+
+const PNG = Buffer.from([0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a]);
+
+describe('Doctor', () => {
+  let projectDir: string;
+
+  const writeProjectFile = (file: string, content: Buffer | string) => {
+    const path = resolve(projectDir, file);
+    mkdirSync(dirname(path), { recursive: true });
+    writeFileSync(path, content);
+    return path;
+  };
+
+  beforeEach(() => {
+    projectDir = mkdtempSync(resolve(tmpdir(), 'staticstripes-doctor-'));
+  });
+
+  afterEach(() => {
+    rmSync(projectDir, { recursive: true, force: true });
+  });
+
+  it('should take the font families of the CSS', () => {
+    const css = `
+      .title { font-family: "Brand Sans", 'Open Sans', sans-serif; }
+      .caption{font-family:Body,system-ui}
+      .code { font-family: monospace; }
+    `;
+
+    expect(getCssFontFamilies(css)).toEqual([
+      'Brand Sans',
+      'Open Sans',
+      'Body',
+    ]);
+    // fonts of <fonts> are embedded, they needn't be installed
+    expect(
+      getCssFontFamilies(css, [
+        { family: 'brand sans', weight: '400', style: 'normal' },
+        { family: 'Body', weight: '700', style: 'normal' },
+      ]),
+    ).toEqual(['Open Sans']);
+  });
+
+  it('should find empty cache files and PNGs without a PNG signature', () => {
+    writeProjectFile('cache/containers/ok.png', PNG);
+    writeProjectFile('cache/apps/ok.apng', PNG);
+    writeProjectFile('cache/conform/ok.mkv', 'video');
+    const empty = writeProjectFile('cache/containers/empty.png', '');
+    const html = writeProjectFile('cache/containers/error.png', '<html>');
+    const emptyFont = writeProjectFile('cache/fonts/empty.ttf', '');
+
+    expect(findBrokenCacheFiles(projectDir).sort()).toEqual(
+      [empty, html, emptyFont].sort(),
+    );
+    expect(checkCache(projectDir).status).toBe('warn');
+
+    rmSync(empty);
+    rmSync(html);
+    rmSync(emptyFont);
+    expect(findBrokenCacheFiles(projectDir)).toEqual([]);
+    expect(checkCache(projectDir)).toMatchObject({
+      status: 'pass',
+      message: expect.stringContaining('3 file(s)'),
+    });
+  });
+
+  it('should pass without a cache', () => {
+    expect(findBrokenCacheFiles(projectDir)).toEqual([]);
+    expect(checkCache(projectDir)).toMatchObject({
+      status: 'pass',
+      message: 'empty',
+    });
+  });
+
+  it('should check that declared font files exist', async () => {
+    const path = writeProjectFile('fonts/Brand.ttf', 'font');
+    const fonts = [
+      { family: 'Brand', weight: '400', style: 'normal' as const, path },
+    ];
+
+    expect((await checkDeclaredFonts(fonts, projectDir)).status).toBe('pass');
+    expect(
+      await checkDeclaredFonts(
+        [...fonts, { ...fonts[0], path: resolve(projectDir, 'missing.ttf') }],
+        projectDir,
+      ),
+    ).toMatchObject({
+      status: 'fail',
+      message: expect.stringContaining('missing.ttf'),
+    });
+  });
+
+  it('should count the checks per status', () => {
+    const checks: DoctorCheck[] = [
+      { name: 'Node.js', status: 'pass', message: 'v22.0.0' },
+      { name: 'ffmpeg', status: 'pass', message: '7.0' },
+      { name: 'GPU encoders', status: 'warn', message: 'none' },
+      { name: 'Browser', status: 'fail', message: 'not found' },
+    ];
+
+    expect(summarizeChecks(checks)).toEqual({ pass: 2, warn: 1, fail: 1 });
+    expect(summarizeChecks([])).toEqual({ pass: 0, warn: 0, fail: 0 });
+  });
+});
@@ -0,0 +1,463 @@
+import { execFile } from 'child_process';
+import { promisify } from 'util';
+import {
+  accessSync,
+  constants,
+  existsSync,
+  openSync,
+  readSync,
+  closeSync,
+  readdirSync,
+  statSync,
+} from 'fs';
+import { statfs } from 'fs/promises';
+import { dirname, resolve } from 'path';
+import puppeteer from 'puppeteer';
+import { Project } from './project';
+import { Font } from './type';
+import { getGoogleFontCachePath, getGoogleFontCssUrl } from './fonts';
+
+const execFileAsync = promisify(execFile);
+
+export type DoctorStatus = 'pass' | 'warn' | 'fail';
+
+export type DoctorCheck = {
+  name: string;
+  status: DoctorStatus;
+  message: string;
+  hint?: string; // how to fix it
+};
+
+export type DoctorSummary = Record<DoctorStatus, number>;
+
+const MIN_NODE_MAJOR = 22; // package.json engines
+const MIN_FREE_SPACE = 512 * 1024 * 1024; // fail below
+const LOW_FREE_SPACE = 5 * 1024 * 1024 * 1024; // warn below
+
+// Hardware encoders FFmpeg may be built with
+const GPU_ENCODERS = [
+  'h264_nvenc',
+  'hevc_nvenc',
+  'h264_videotoolbox',
+  'hevc_videotoolbox',
+  'h264_qsv',
+  'hevc_qsv',
+  'h264_vaapi',
+  'hevc_vaapi',
+  'h264_amf',
+  'hevc_amf',
+];
+
+// CSS generic families, always available
+const GENERIC_FONT_FAMILIES = new Set([
+  'serif',
+  'sans-serif',
+  'monospace',
+  'cursive',
+  'fantasy',
+  'system-ui',
+  'ui-serif',
+  'ui-sans-serif',
+  'ui-monospace',
+  'ui-rounded',
+  'emoji',
+  'math',
+  'fangsong',
+  'inherit',
+  'initial',
+  'unset',
+]);
+
+const PNG_SIGNATURE = Buffer.from([0x89, 0x50, 0x4e, 0x47]);
+
+function formatBytes(bytes: number): string {
+  if (bytes >= 1024 * 1024 * 1024) {
+    return `${(bytes / 1024 / 1024 / 1024).toFixed(1)} GB`;
+  }
+  return `${(bytes / 1024 / 1024).toFixed(1)} MB`;
+}
+
+/**
+ * Counts the checks per status, doctor fails if any check failed
+ */
+export function summarizeChecks(checks: DoctorCheck[]): DoctorSummary {
+  const summary: DoctorSummary = { pass: 0, warn: 0, fail: 0 };
+  for (const check of checks) {
+    summary[check.status]++;
+  }
+  return summary;
+}
+
+export function checkNode(): DoctorCheck {
+  const major = parseInt(process.versions.node.split('.')[0], 10);
+
+  return major >= MIN_NODE_MAJOR
+    ? { name: 'Node.js', status: 'pass', message: process.version }
+    : {
+        name: 'Node.js',
+        status: 'fail',
+        message: `${process.version}, ${MIN_NODE_MAJOR} or newer is required`,
+        hint: 'Install a current Node.js LTS from https://nodejs.org',
+      };
+}
+
+/**
+ * Checks that an FFmpeg tool runs and reports its version
+ */
+export async function checkTool(tool: 'ffmpeg' | 'ffprobe'): Promise<DoctorCheck> {
+  try {
+    const { stdout } = await execFileAsync(tool, ['-version']);
+    return {
+      name: tool,
+      status: 'pass',
+      message: stdout.split('\n')[0].trim(),
+    };
+  } catch {
+    return {
+      name: tool,
+      status: 'fail',
+      message: 'not found in PATH',
+      hint: 'Install FFmpeg: https://ffmpeg.org/download.html (brew install ffmpeg, apt-get install ffmpeg)',
+    };
+  }
+}
+
+export async function checkGpuEncoders(): Promise<DoctorCheck> {
+  try {
+    const { stdout } = await execFileAsync(
+      'ffmpeg',
+      ['-hide_banner', '-encoders'],
+      { maxBuffer: 10 * 1024 * 1024 },
+    );
+    const available = GPU_ENCODERS.filter((encoder) =>
+      new RegExp(`\\s${encoder}\\s`).test(stdout),
+    );
+
+    return available.length > 0
+      ? {
+          name: 'GPU encoders',
+          status: 'pass',
+          message: available.join(', '),
+        }
+      : {
+          name: 'GPU encoders',
+          status: 'warn',
+          message: 'none, rendering uses CPU encoders only',
+          hint: 'Optional: an FFmpeg build with nvenc/videotoolbox/qsv/vaapi speeds up <ffmpeg> presets that use them',
+        };
+  } catch {
+    return {
+      name: 'GPU encoders',
+      status: 'warn',
+      message: 'could not list FFmpeg encoders',
+    };
+  }
+}
+
+/**
+ * Checks that Puppeteer's browser, used to render containers and apps, exists
+ */
+export function checkBrowser(): DoctorCheck {
+  let executablePath = '';
+  try {
+    executablePath = puppeteer.executablePath();
+  } catch {
+    // reported below
+  }
+
+  return executablePath && existsSync(executablePath)
+    ? { name: 'Browser', status: 'pass', message: executablePath }
+    : {
+        name: 'Browser',
+        status: 'fail',
+        message: 'Puppeteer browser not found, containers and apps cannot be rendered',
+        hint: 'Run: npx puppeteer browsers install chrome',
+      };
+}
+
+export async function checkDiskSpace(dir: string): Promise<DoctorCheck> {
+  try {
+    const stats = await statfs(dir);
+    const free = stats.bavail * stats.bsize;
+
+    if (free < MIN_FREE_SPACE) {
+      return {
+        name: 'Disk space',
+        status: 'fail',
+        message: `${formatBytes(free)} free in ${dir}`,
+        hint: 'Free up space, renders and caches need room for intermediate files',
+      };
+    }
+
+    return {
+      name: 'Disk space',
+      status: free < LOW_FREE_SPACE ? 'warn' : 'pass',
+      message: `${formatBytes(free)} free in ${dir}`,
+    };
+  } catch (error) {
+    return {
+      name: 'Disk space',
+      status: 'warn',
+      message: `could not be determined: ${error instanceof Error ? error.message : String(error)}`,
+    };
+  }
+}
+
+/**
+ * Font families used by the CSS, without the generic ones and the ones
+ * declared in <fonts> (embedded into the pages, they needn't be installed)
+ */
+export function getCssFontFamilies(
+  cssText: string,
+  declaredFonts: Font[] = [],
+): string[] {
+  const declared = new Set(
+    declaredFonts.map((font) => font.family.toLowerCase()),
+  );
+  const families = new Set<string>();
+  for (const match of cssText.matchAll(/font-family\s*:\s*([^;}]+)/gi)) {
+    for (const family of match[1].split(',')) {
+      const name = family.trim().replace(/^["']|["']$/g, '');
+      const key = name.toLowerCase();
+      if (name && !GENERIC_FONT_FAMILIES.has(key) && !declared.has(key)) {
+        families.add(name);
+      }
+    }
+  }
+  return Array.from(families);
+}
+
+/**
+ * Finds font families used by the project's CSS that are neither declared
+ * in <fonts> nor installed (checked with fontconfig, where available)
+ */
+export async function checkFonts(
+  cssText: string,
+  declaredFonts: Font[] = [],
+): Promise<DoctorCheck> {
+  const families = getCssFontFamilies(cssText, declaredFonts);
+
+  if (families.length === 0) {
+    return {
+      name: 'Fonts',
+      status: 'pass',
+      message:
+        declaredFonts.length > 0
+          ? 'project uses declared and generic font families only'
+          : 'project uses generic font families only',
+    };
+  }
+
+  let installed: Set<string>;
+  try {
+    const { stdout } = await execFileAsync('fc-list', [':', 'family'], {
+      maxBuffer: 10 * 1024 * 1024,
+    });
+    installed = new Set(
+      stdout
+        .split('\n')
+        .flatMap((line) => line.split(','))
+        .map((family) => family.trim().toLowerCase())
+        .filter(Boolean),
+    );
+  } catch {
+    return {
+      name: 'Fonts',
+      status: 'warn',
+      message: `fc-list not available, could not verify: ${families.join(', ')}`,
+    };
+  }
+
+  const missing = families.filter(
+    (family) => !installed.has(family.toLowerCase()),
+  );
+
+  return missing.length === 0
+    ? {
+        name: 'Fonts',
+        status: 'pass',
+        message: families.join(', '),
+      }
+    : {
+        name: 'Fonts',
+        status: 'fail',
+        message: `not installed: ${missing.join(', ')}`,
+        hint: 'Declare the fonts in <fonts> (or install them system-wide), otherwise the browser silently falls back to another font',
+      };
+}
+
+/**
+ * Checks the fonts of <fonts>: local font files exist, Google fonts are
+ * downloaded to cache/fonts or can be downloaded
+ */
+export async function checkDeclaredFonts(
+  fonts: Font[],
+  projectDir: string,
+): Promise<DoctorCheck> {
+  if (fonts.length === 0) {
+    return { name: 'Declared fonts', status: 'pass', message: 'none' };
+  }
+
+  const missing = fonts
+    .filter((font) => !font.google && !(font.path && existsSync(font.path)))
+    .map((font) => `${font.family} (${font.path ?? 'no src'})`);
+  if (missing.length > 0) {
+    return {
+      name: 'Declared fonts',
+      status: 'fail',
+      message: `font file not found: ${missing.join(', ')}`,
+      hint: 'Fix the src of the <font> elements, src is relative to project.html',
+    };
+  }
+
+  const unreachable: string[] = [];
+  for (const font of fonts) {
+    if (!font.google || existsSync(getGoogleFontCachePath(font, projectDir))) {
+      continue;
+    }
+    try {
+      const response = await fetch(getGoogleFontCssUrl(font), {
+        signal: AbortSignal.timeout(10_000),
+      });
+      if (!response.ok) {
+        unreachable.push(`${font.google} (${response.status})`);
+      }
+    } catch {
+      unreachable.push(font.google);
+    }
+  }
+  if (unreachable.length > 0) {
+    return {
+      name: 'Declared fonts',
+      status: 'fail',
+      message: `Google fonts not cached and not downloadable: ${unreachable.join(', ')}`,
+      hint: 'Check the network and the family names, or use local font files',
+    };
+  }
+
+  return {
+    name: 'Declared fonts',
+    status: 'pass',
+    message: fonts
+      .map((font) => `${font.family} ${font.weight} ${font.style}`)
+      .join(', '),
+  };
+}
+
+/**
+ * Cache files that can't be valid: empty files and PNGs without a PNG signature
+ */
+export function findBrokenCacheFiles(projectDir: string): string[] {
+  const cacheDir = resolve(projectDir, 'cache');
+  if (!existsSync(cacheDir)) {
+    return [];
+  }
+
+  const broken: string[] = [];
+  const scan = (dir: string) => {
+    for (const entry of readdirSync(dir, { withFileTypes: true })) {
+      const path = resolve(dir, entry.name);
+      if (entry.isDirectory()) {
+        scan(path);
+        continue;
+      }
+
+      const { size } = statSync(path);
+      if (size === 0) {
+        broken.push(path);
+        continue;
+      }
+
+      if (entry.name.endsWith('.png') || entry.name.endsWith('.apng')) {
+        const header = Buffer.alloc(PNG_SIGNATURE.length);
+        const fd = openSync(path, 'r');
+        try {
+          readSync(fd, header, 0, header.length, 0);
+        } finally {
+          closeSync(fd);
+        }
+        if (!header.equals(PNG_SIGNATURE)) {
+          broken.push(path);
+        }
+      }
+    }
+  };
+  scan(cacheDir);
+
+  return broken;
+}
+
+export function checkCache(projectDir: string): DoctorCheck {
+  const cacheDir = resolve(projectDir, 'cache');
+  if (!existsSync(cacheDir)) {
+    return { name: 'Cache', status: 'pass', message: 'empty' };
+  }
+
+  let files = 0;
+  let size = 0;
+  const scan = (dir: string) => {
+    for (const entry of readdirSync(dir, { withFileTypes: true })) {
+      const path = resolve(dir, entry.name);
+      if (entry.isDirectory()) {
+        scan(path);
+      } else {
+        files++;
+        size += statSync(path).size;
+      }
+    }
+  };
+  scan(cacheDir);
+
+  const broken = findBrokenCacheFiles(projectDir);
+  if (broken.length > 0) {
+    return {
+      name: 'Cache',
+      status: 'warn',
+      message: `${broken.length} broken file(s) in ${cacheDir}`,
+      hint: 'Run "staticstripes doctor --fix" to remove them, they are re-rendered on the next generate',
+    };
+  }
+
+  return {
+    name: 'Cache',
+    status: 'pass',
+    message: `${files} file(s), ${formatBytes(size)} in ${cacheDir}`,
+  };
+}
+
+/**
+ * Checks that the directory of every output can be written to
+ * (missing directories are created on render, their nearest parent counts)
+ */
+export function checkOutputPaths(project: Project): DoctorCheck {
+  const notWritable: string[] = [];
+
+  for (const output of project.getOutputs().values()) {
+    let dir = dirname(output.path);
+    while (!existsSync(dir) && dirname(dir) !== dir) {
+      dir = dirname(dir);
+    }
+
+    try {
+      accessSync(dir, constants.W_OK);
+      if (existsSync(output.path)) {
+        accessSync(output.path, constants.W_OK);
+      }
+    } catch {
+      notWritable.push(`${output.name} (${output.path})`);
+    }
+  }
+
+  return notWritable.length === 0
+    ? {
+        name: 'Output paths',
+        status: 'pass',
+        message: `${project.getOutputs().size} output(s) writable`,
+      }
+    : {
+        name: 'Output paths',
+        status: 'fail',
+        message: `not writable: ${notWritable.join(', ')}`,
+        hint: 'Fix the directory permissions or change the output path',
+      };
+}
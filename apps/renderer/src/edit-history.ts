@@ -0,0 +1,83 @@
+import {
+  existsSync,
+  mkdirSync,
+  readdirSync,
+  readFileSync,
+  unlinkSync,
+  writeFileSync,
+} from 'fs';
+import { resolve } from 'path';
+
+export const EDIT_HISTORY_DIR = '.staticstripes/history';
+
+const MAX_SNAPSHOTS = 50;
+
+function getHistoryDir(projectDir: string): string {
+  return resolve(projectDir, EDIT_HISTORY_DIR);
+}
+
+/**
+ * Snapshot files, oldest first (names start with a sortable timestamp)
+ */
+export function listSnapshots(projectDir: string): string[] {
+  const dir = getHistoryDir(projectDir);
+  if (!existsSync(dir)) {
+    return [];
+  }
+
+  return readdirSync(dir)
+    .filter((name) => name.endsWith('.html'))
+    .sort()
+    .map((name) => resolve(dir, name));
+}
+
+/**
+ * Saves the project file before an edit, so it can be undone.
+ * Only the latest snapshots are kept.
+ * @param operation - Name of the edit, part of the snapshot file name
+ */
+export function saveSnapshot(
+  projectDir: string,
+  projectFilePath: string,
+  operation: string,
+): string {
+  const dir = getHistoryDir(projectDir);
+  mkdirSync(dir, { recursive: true });
+
+  const timestamp = new Date().toISOString().replace(/[:.]/g, '-');
+  const existing = listSnapshots(projectDir);
+  // Several edits within one millisecond still sort in order
+  const sequence = String(existing.length).padStart(4, '0');
+  const path = resolve(
+    dir,
+    `${timestamp}_${sequence}_${operation.replace(/[^a-z0-9-]/gi, '-')}.html`,
+  );
+  writeFileSync(path, readFileSync(projectFilePath));
+
+  const snapshots = [...existing, path];
+  snapshots
+    .slice(0, Math.max(0, snapshots.length - MAX_SNAPSHOTS))
+    .forEach((snapshot) => unlinkSync(snapshot));
+
+  return path;
+}
+
+/**
+ * Restores the project file from the latest snapshot and removes it,
+ * so repeated undos walk back through the history
+ * @returns The restored snapshot, undefined if there is nothing to undo
+ */
+export function restoreLatestSnapshot(
+  projectDir: string,
+  projectFilePath: string,
+): string | undefined {
+  const latest = listSnapshots(projectDir).pop();
+  if (!latest) {
+    return undefined;
+  }
+
+  writeFileSync(projectFilePath, readFileSync(latest));
+  unlinkSync(latest);
+
+  return latest;
+}
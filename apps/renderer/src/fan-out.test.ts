@@ -0,0 +1,102 @@
+import { describe, it, expect } from 'vitest';
+import {
+  canShareComposite,
+  groupOutputsForFanOut,
+  getFanOutPrimary,
+} from './fan-out';
+import { Output } from './type';
+
+const makeOutput = (
+  name: string,
+  width: number,
+  height: number,
+  extra: Partial<Output> = {},
+): Output => ({
+  name,
+  path: `/tmp/test/output/${name}.mp4`,
+  resolution: { width, height },
+  fps: 30,
+  ...extra,
+});
+
+describe('Output fan-out', () => {
+  it('should share a composite between outputs with the same aspect ratio and fps', () => {
+    expect(
+      canShareComposite(
+        makeOutput('hd', 1920, 1080),
+        makeOutput('sd', 1280, 720),
+      ),
+    ).toBe(true);
+    expect(
+      canShareComposite(
+        makeOutput('hd', 1920, 1080),
+        makeOutput('vertical', 1080, 1920),
+      ),
+    ).toBe(false);
+    expect(
+      canShareComposite(
+        makeOutput('hd', 1920, 1080),
+        makeOutput('hd60', 1280, 720, { fps: 60 }),
+      ),
+    ).toBe(false);
+    expect(
+      canShareComposite(
+        makeOutput('hd', 1920, 1080),
+        makeOutput('podcast', 1920, 1080, { format: 'mp3' }),
+      ),
+    ).toBe(false);
+  });
+
+  it('should group outputs in order of appearance', () => {
+    const groups = groupOutputsForFanOut([
+      makeOutput('youtube', 1920, 1080),
+      makeOutput('shorts', 1080, 1920),
+      makeOutput('preview', 1280, 720),
+      makeOutput('podcast', 1920, 1080, { format: 'mp3' }),
+      makeOutput('tiktok', 720, 1280),
+    ]);
+
+    expect(groups.map((group) => group.map((output) => output.name))).toEqual(
+      [['youtube', 'preview'], ['shorts', 'tiktok'], ['podcast']],
+    );
+  });
+
+  it('should share a composite only between outputs scaling text alike', () => {
+    const baseSize = { width: 1920, height: 1080 };
+    const groups = groupOutputsForFanOut(
+      [
+        makeOutput('master', 3840, 2160, { baseSize }),
+        makeOutput('proxy', 1280, 720, { baseSize }),
+        makeOutput('vertical', 1080, 1920, { baseSize }),
+        makeOutput('mobile', 1080, 1920, {
+          baseSize: { width: 1080, height: 1920 },
+        }),
+      ],
+      true,
+    );
+
+    expect(groups.map((group) => group.map((output) => output.name))).toEqual(
+      [['master', 'proxy'], ['vertical'], ['mobile']],
+    );
+  });
+
+  it('should not downscale px text of containers and apps', () => {
+    const outputs = [
+      makeOutput('uhd', 3840, 2160),
+      makeOutput('proxy', 1280, 720),
+    ];
+
+    expect(groupOutputsForFanOut(outputs, true)).toHaveLength(2);
+    expect(groupOutputsForFanOut(outputs, false)).toHaveLength(1);
+  });
+
+  it('should build the composite at the largest output', () => {
+    const primary = getFanOutPrimary([
+      makeOutput('preview', 1280, 720),
+      makeOutput('uhd', 3840, 2160),
+      makeOutput('youtube', 1920, 1080),
+    ]);
+
+    expect(primary.name).toBe('uhd');
+  });
+});
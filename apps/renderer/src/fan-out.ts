@@ -0,0 +1,76 @@
+import { Output } from './type';
+import { getRootFontSize } from './container-renderer';
+
+/**
+ * Whether the containers of two outputs look the same when one is scaled to
+ * the other: 1rem is the same share of the frame width. Without a base size
+ * text has the same px size in every output, so only outputs of the same
+ * width qualify. The root font size is rounded, hence the tolerance.
+ */
+function scalesTextEquivalently(a: Output, b: Output): boolean {
+  const remA = getRootFontSize(a) / a.resolution.width;
+  const remB = getRootFontSize(b) / b.resolution.width;
+  return Math.abs(remA - remB) / Math.max(remA, remB) < 0.005;
+}
+
+/**
+ * Whether two outputs can be encoded from one composite: video outputs with
+ * the same frame rate and aspect ratio, differing only by resolution/bitrate.
+ * Containers and apps are rendered once, at the largest output, so with
+ * them the outputs must also scale text alike.
+ */
+export function canShareComposite(
+  a: Output,
+  b: Output,
+  hasContainersOrApps: boolean = false,
+): boolean {
+  return (
+    !a.format &&
+    !b.format &&
+    a.fps === b.fps &&
+    a.resolution.width * b.resolution.height ===
+      a.resolution.height * b.resolution.width &&
+    (!hasContainersOrApps || scalesTextEquivalently(a, b))
+  );
+}
+
+/**
+ * Groups outputs that can share one composite, in order of appearance.
+ * Audio-only outputs always stay alone.
+ */
+export function groupOutputsForFanOut(
+  outputs: Output[],
+  hasContainersOrApps: boolean = false,
+): Output[][] {
+  const groups: Output[][] = [];
+
+  for (const output of outputs) {
+    const group = groups.find((candidate) =>
+      canShareComposite(candidate[0], output, hasContainersOrApps),
+    );
+    if (group) {
+      group.push(output);
+    } else {
+      groups.push([output]);
+    }
+  }
+
+  return groups;
+}
+
+/**
+ * The output a shared composite is built at: the largest one,
+ * so the other outputs are only downscaled
+ */
+export function getFanOutPrimary(outputs: Output[]): Output {
+  if (outputs.length === 0) {
+    throw new Error('getFanOutPrimary: outputs cannot be empty');
+  }
+
+  return outputs.reduce((largest, output) =>
+    output.resolution.width * output.resolution.height >
+    largest.resolution.width * largest.resolution.height
+      ? output
+      : largest,
+  );
+}
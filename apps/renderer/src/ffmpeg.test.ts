@@ -0,0 +1,44 @@
+import { describe, it, expect } from 'vitest';
+import { makeKenBurns } from './ffmpeg';
+
+describe('makeKenBurns', () => {
+  const options = {
+    effectDuration: 0,
+    fragmentDuration: 3000,
+    easing: 'linear' as const,
+    width: 1920,
+    height: 1080,
+    fps: 30,
+  };
+
+  it('should zoom from the base scale over the fragment', () => {
+    const filter = makeKenBurns([{ tag: 'v0', isAudio: false }], {
+      ...options,
+      effect: 'zoom-in',
+      zoom: 15,
+    });
+
+    expect(filter.render()).toContain("z='1+(1.15-1)*(min(1,on/90))'");
+  });
+
+  it('should zoom out to a base scale above 1.0', () => {
+    const filter = makeKenBurns([{ tag: 'v0', isAudio: false }], {
+      ...options,
+      effect: 'zoom-out',
+      zoom: 30,
+      zoomBase: 10,
+    });
+
+    expect(filter.render()).toContain("z='1.3-(1.3-1.1)*(min(1,on/90))'");
+  });
+
+  it('should only take video inputs', () => {
+    expect(() =>
+      makeKenBurns([{ tag: '0:a', isAudio: true }], {
+        ...options,
+        effect: 'zoom-in',
+        zoom: 15,
+      }),
+    ).toThrow('makeKenBurns: input must be video');
+  });
+});
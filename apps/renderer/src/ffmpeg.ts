@@ -1,6 +1,8 @@
 import { spawn } from 'child_process';
 import { getLabel } from './label-generator';
 import { Project } from './project';
+import { AssetManager } from './asset-manager';
+import { Asset, Output } from './type';
 
 export type Label = {
   tag: string;
@@ -89,6 +91,11 @@ export class Filter {
   }
 }
 
+/**
+ * Time range of the timeline to encode (ms), e.g. between two markers
+ */
+export type TimeRange = { start: Millisecond; end: Millisecond };
+
 /**
  * Generates the complete ffmpeg command for rendering the project
  */
@@ -97,20 +104,123 @@ export function makeFFmpegCommand(
   filterComplex: string,
   outputName: string,
   ffmpegArgs?: string,
+  range?: TimeRange,
+): string {
+  const output = project.getOutput(outputName);
+  if (!output) {
+    throw new Error(`Output "${outputName}" not found`);
+  }
+
+  return makeFFmpegCommandForAssets(
+    project.getAssetManager(),
+    output,
+    filterComplex,
+    ffmpegArgs,
+    range,
+  );
+}
+
+/**
+ * Generates an ffmpeg command for a filter graph over the given assets
+ * (the whole project, or a standalone part of it)
+ */
+export function makeFFmpegCommandForAssets(
+  assetManager: AssetManager,
+  output: Output,
+  filterComplex: string,
+  ffmpegArgs?: string,
+  range?: TimeRange,
 ): string {
   const parts: string[] = ['ffmpeg'];
 
   // Overwrite output file without asking
   parts.push('-y');
 
-  // Add input files in order of their index mapping
-  const inputsByIndex = new Map<number, string>();
+  parts.push(...makeInputParts(assetManager));
+
+  // Add filter_complex
+  if (filterComplex) {
+    parts.push(`-filter_complex "${filterComplex}"`);
+  }
+
+  parts.push(...makeOutputParts(output, 'outv', 'outa', ffmpegArgs, range));
+
+  return parts.join(' ');
+}
+
+/**
+ * Generates one ffmpeg command that encodes several outputs from a shared
+ * filter graph (see Project.buildFanOut), the n-th output is mapped from
+ * [outv_n] and [outa_n]
+ */
+export function makeFanOutFFmpegCommand(
+  assetManager: AssetManager,
+  targets: { output: Output; ffmpegArgs?: string }[],
+  filterComplex: string,
+  range?: TimeRange,
+): string {
+  const parts: string[] = ['ffmpeg', '-y'];
+
+  parts.push(...makeInputParts(assetManager));
+  parts.push(`-filter_complex "${filterComplex}"`);
+
+  targets.forEach(({ output, ffmpegArgs }, index) => {
+    parts.push(
+      ...makeOutputParts(
+        output,
+        `outv_${index}`,
+        `outa_${index}`,
+        ffmpegArgs,
+        range,
+      ),
+    );
+  });
+
+  return parts.join(' ');
+}
+
+/**
+ * Generates an ffmpeg command that renders a single frame of the project
+ * as raw rgb24 pixels (width * height * 3 bytes), e.g. to check pixel colors
+ * @param time - Time of the frame on the timeline, in ms
+ */
+export function makeFrameFFmpegCommand(
+  assetManager: AssetManager,
+  output: Output,
+  filterComplex: string,
+  time: Millisecond,
+  framePath: string,
+): string {
+  const { width, height } = output.resolution;
+
+  return [
+    'ffmpeg',
+    '-y',
+    ...makeInputParts(assetManager),
+    // the audio of the project is not needed
+    `-filter_complex "${filterComplex};[outa]anullsink"`,
+    '-map "[outv]"',
+    `-ss ${ms(time)}`,
+    '-frames:v 1',
+    `-s ${width}x${height}`,
+    '-f rawvideo',
+    '-pix_fmt rgb24',
+    `"${framePath}"`,
+  ].join(' ');
+}
+
+/**
+ * Input files of a command, in order of their index mapping
+ */
+function makeInputParts(assetManager: AssetManager): string[] {
+  const parts: string[] = [];
+  const inputsByIndex = new Map<number, Asset>();
   const missingAssets: string[] = [];
 
-  for (const [assetName, index] of project.getAssetIndexMap()) {
-    const asset = project.getAssetByName(assetName);
+  for (const [assetName, index] of assetManager.getAssetIndexMap()) {
+    const asset = assetManager.getAssetByName(assetName);
     if (asset) {
-      inputsByIndex.set(index, asset.path);
+      inputsByIndex.set(index, asset);
     } else {
       missingAssets.push(`${assetName} (index ${index})`);
     }
@@ -127,48 +237,117 @@ export function makeFFmpegCommand(
   // Add inputs in sorted order
   const sortedIndices = Array.from(inputsByIndex.keys()).sort((a, b) => a - b);
   for (const index of sortedIndices) {
-    const path = inputsByIndex.get(index);
-    if (path) {
-      parts.push(`-i "${path}"`);
+    const asset = inputsByIndex.get(index);
+    if (asset) {
+      // Rotation metadata is applied in the filter graph (see Asset.rotation
+      // and -orient), FFmpeg must not rotate the frames on its own
+      parts.push('-noautorotate');
+      // e.g. libvpx-vp9, the native decoder drops the alpha of VP9 WebM
+      if (asset.alphaDecoder) {
+        parts.push(`-c:v ${asset.alphaDecoder}`);
+      }
+      parts.push(`-i "${asset.path}"`);
     }
   }
 
-  // Add filter_complex
-  if (filterComplex) {
-    parts.push(`-filter_complex "${filterComplex}"`);
-  }
+  return parts;
+}
 
-  // Map the output streams (video and audio)
-  parts.push('-map "[outv]"');
-  parts.push('-map "[outa]"');
+/**
+ * Stream mapping, output parameters and path of one output of a command
+ */
+function makeOutputParts(
+  output: Output,
+  videoTag: string,
+  audioTag: string,
+  ffmpegArgs?: string,
+  range?: TimeRange,
+): string[] {
+  const parts: string[] = [];
+
+  // Map the output streams (video and audio, audio only for audio formats)
+  if (output.format) {
+    parts.push('-vn');
+  } else {
+    parts.push(`-map "[${videoTag}]"`);
+  }
+  parts.push(`-map "[${audioTag}]"`);
 
   // Increase buffer queue size for complex filter graphs
   parts.push('-max_muxing_queue_size 4096');
 
-  // Add output parameters
-  const output = project.getOutput(outputName);
-  if (!output) {
-    throw new Error(`Output "${outputName}" not found`);
+  // Only a part of the timeline: output seeking, the graph still runs from 0
+  // so everything before the range is rendered exactly as in the full video
+  if (range) {
+    parts.push(`-ss ${ms(range.start)}`);
+    parts.push(`-t ${ms(range.end - range.start)}`);
   }
 
-  const { width, height } = output.resolution;
-
   // Add standard output parameters
-  parts.push(`-s ${width}x${height}`);
-  parts.push(`-r ${output.fps}`);
+  if (!output.format) {
+    const { width, height } = output.resolution;
+    parts.push(`-s ${width}x${height}`);
+    parts.push(`-r ${output.fps}`);
+  }
 
   // Add FFmpeg arguments (encoding parameters, codecs, etc.)
   if (ffmpegArgs) {
     parts.push(ffmpegArgs);
   }
 
+  // Per-output bitrate goes last, so it overrides the one of the arguments
+  if (output.bitrate) {
+    parts.push(`${output.format ? '-b:a' : '-b:v'} ${output.bitrate}`);
+  }
+
   // Add output path
   parts.push(`"${output.path}"`);
 
-  return parts.join(' ');
+  return parts;
+}
+
+/**
+ * Returns the default encoding arguments for an output,
+ * used when no FFmpeg option preset is selected
+ */
+export function getDefaultFFmpegArgs(output: Output): string {
+  if (output.alpha) {
+    // Codecs that keep transparency, by container
+    return output.path.toLowerCase().endsWith('.webm')
+      ? '-c:v libvpx-vp9 -pix_fmt yuva420p -b:v 0 -crf 30 -auto-alt-ref 0 -c:a libopus -b:a 192k'
+      : '-c:v prores_ks -profile:v 4444 -pix_fmt yuva444p10le -alpha_bits 16 -c:a pcm_s16le';
+  }
+
+  switch (output.format) {
+    case 'mp3':
+      return '-c:a libmp3lame -b:a 192k';
+    case 'aac':
+      return '-c:a aac -b:a 192k';
+    case 'flac':
+      return '-c:a flac';
+    default:
+      return '-c:v libx264 -pix_fmt yuv420p -preset medium -c:a aac -b:a 192k';
+  }
 }
 
-export const runFFMpeg = async (ffmpegCommand: string) => {
+/**
+ * Error of a failed FFmpeg run, keeps the stderr output for diagnostics
+ */
+export class FFmpegError extends Error {
+  constructor(
+    message: string,
+    public stderr: string,
+    public exitCode: number | null,
+  ) {
+    super(message);
+    this.name = 'FFmpegError';
+  }
+}
+
+export const runFFMpeg = async (
+  ffmpegCommand: string,
+  options: { silent?: boolean } = {},
+) => {
   const args =
     ffmpegCommand
       .slice('ffmpeg '.length)
@@ -187,18 +366,30 @@ export const runFFMpeg = async (ffmpegCommand: string) => {
       stderrBuffer += output;
 
       // Show all output for debugging
-      process.stderr.write(output);
+      if (!options.silent) {
+        process.stderr.write(output);
+      }
     });
 
     ffmpeg.on('close', (code) => {
-      process.stdout.write('\n');
+      if (!options.silent) {
+        process.stdout.write('\n');
+      }
       if (code === 0) {
-        console.log('\n=== Render Complete ===');
+        if (!options.silent) {
+          console.log('\n=== Render Complete ===');
+        }
         resolve();
       } else {
         console.error(`\n=== Render Failed ===`);
         console.error(`FFmpeg exited with code ${code}`);
-        reject(new Error(`FFmpeg process exited with code ${code}`));
+        reject(
+          new FFmpegError(
+            `FFmpeg process exited with code ${code}`,
+            stderrBuffer,
+            code,
+          ),
+        );
       }
     });
 
@@ -478,26 +669,27 @@ export function makeScale(
 
 /**
  * Creates a split filter (splits one input into multiple outputs)
- * @param input - Input stream label
- * @param outputLabels - Array of output stream labels
+ * Audio inputs use asplit
+ * @param inputs - Input stream label
+ * @param count - Number of outputs
  */
-export function makeSplit(inputs: Label[]): Filter {
+export function makeSplit(inputs: Label[], count = 2): Filter {
   if (inputs.length !== 1) {
-    throw new Error(`makeFps: expects one input`);
+    throw new Error(`makeSplit: expects one input`);
   }
 
   const input1 = inputs[0];
 
-  const output1 = {
-    tag: getLabel(),
-    isAudio: input1.isAudio,
-  };
-  const output2 = {
-    tag: getLabel(),
-    isAudio: input1.isAudio,
-  };
+  const outputs: Label[] = [];
+  for (let i = 0; i < count; i++) {
+    outputs.push({
+      tag: getLabel(),
+      isAudio: input1.isAudio,
+    });
+  }
 
-  return new Filter(inputs, [output1, output2], 'split');
+  const name = input1.isAudio ? 'asplit' : 'split';
+  return new Filter(inputs, outputs, count === 2 ? name : `${name}=${count}`);
 }
 
 export function makeTranspose(
@@ -1223,6 +1415,7 @@ export function makeKenBurns(
   options: {
     effect: 'zoom-in' | 'zoom-out' | 'pan-left' | 'pan-right' | 'pan-top' | 'pan-bottom';
     zoom: number;
+    zoomBase?: number;
     effectDuration: number;
     fragmentDuration: number;
     easing: 'linear' | 'ease-in' | 'ease-out' | 'ease-in-out';
@@ -1266,6 +1459,8 @@ export function makeKenBurns(
 
   // Convert zoom percentage to factor (e.g., 30% = 1.3x)
   const zoomFactor = 1 + options.zoom / 100;
+  // Factor zoom-in starts from and zoom-out ends at (1 = the whole frame)
+  const baseFactor = 1 + (options.zoomBase ?? 0) / 100;
 
   // Create easing function expression
   // t = progress from 0 to 1 (on/animationFrames)
@@ -1296,15 +1491,15 @@ export function makeKenBurns(
 
   switch (options.effect) {
     case 'zoom-in':
-      // Start at 1.0, zoom to zoomFactor over animation duration, then hold
-      zoomExpr = `'1+(${zoomFactor}-1)*(${progress})'`;
+      // Start at baseFactor, zoom to zoomFactor over animation duration, then hold
+      zoomExpr = `'${baseFactor}+(${zoomFactor}-${baseFactor})*(${progress})'`;
       xExpr = `'iw*${focalX/100}-iw/zoom/2'`;
       yExpr = `'ih*${focalY/100}-ih/zoom/2'`;
       break;
 
     case 'zoom-out':
-      // Start at zoomFactor, zoom out to 1.0 over animation duration, then hold
-      zoomExpr = `'${zoomFactor}-(${zoomFactor}-1)*(${progress})'`;
+      // Start at zoomFactor, zoom out to baseFactor over animation duration, then hold
+      zoomExpr = `'${zoomFactor}-(${zoomFactor}-${baseFactor})*(${progress})'`;
       xExpr = `'iw*${focalX/100}-iw/zoom/2'`;
       yExpr = `'ih*${focalY/100}-ih/zoom/2'`;
       break;
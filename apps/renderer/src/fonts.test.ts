@@ -0,0 +1,146 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { mkdtempSync, rmSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import { resolve } from 'path';
+import {
+  findMissingGlyphs,
+  getGoogleFontCssUrl,
+  getHtmlText,
+  makeFontFaceCss,
+  readCharacterMap,
+  resolveFonts,
+} from './fonts';
+
+// A minimal TrueType file: just a cmap (format 4) with the given ranges
+const makeFont = (ranges: [number, number][]): Buffer => {
+  const segments = [...ranges, [0xffff, 0xffff]];
+  const segCount = segments.length;
+
+  const subtable = Buffer.alloc(16 + segCount * 8);
+  subtable.writeUInt16BE(4, 0);
+  subtable.writeUInt16BE(subtable.length, 2);
+  subtable.writeUInt16BE(segCount * 2, 6);
+  segments.forEach(([start, end], i) => {
+    subtable.writeUInt16BE(end, 14 + i * 2);
+    subtable.writeUInt16BE(start, 16 + segCount * 2 + i * 2);
+    // maps the range to glyphs 1, 2, 3... (the delta is modulo 65536)
+    subtable.writeUInt16BE((1 - start) & 0xffff, 16 + segCount * 4 + i * 2);
+  });
+
+  const font = Buffer.alloc(40 + subtable.length);
+  font.writeUInt32BE(0x00010000, 0);
+  font.writeUInt16BE(1, 4);
+  font.write('cmap', 12, 'latin1');
+  font.writeUInt32BE(28, 20);
+  font.writeUInt32BE(12 + subtable.length, 24);
+  font.writeUInt16BE(1, 30); // one subtable: Windows, Unicode BMP
+  font.writeUInt16BE(3, 32);
+  font.writeUInt16BE(1, 34);
+  font.writeUInt32BE(12, 36);
+  subtable.copy(font, 40);
+  return font;
+};
+
+describe('Fonts', () => {
+  let dir: string;
+
+  beforeEach(() => {
+    dir = mkdtempSync(resolve(tmpdir(), 'staticstripes-fonts-'));
+  });
+
+  afterEach(() => {
+    rmSync(dir, { recursive: true, force: true });
+  });
+
+  it('should read the characters a font has glyphs for', () => {
+    const codePoints = readCharacterMap(
+      makeFont([
+        [0x20, 0x20],
+        [0x41, 0x5a],
+      ]),
+    );
+
+    expect(codePoints?.has('A'.codePointAt(0)!)).toBe(true);
+    expect(codePoints?.has('Z'.codePointAt(0)!)).toBe(true);
+    expect(codePoints?.has('a'.codePointAt(0)!)).toBe(false);
+    expect(readCharacterMap(Buffer.from('wOFF0000000000000000'))).toBe(
+      undefined,
+    );
+  });
+
+  it('should find characters without a glyph', () => {
+    const fonts = [
+      {
+        family: 'Brand',
+        weight: '400',
+        style: 'normal' as const,
+        file: '/tmp/brand.ttf',
+        codePoints: readCharacterMap(makeFont([[0x41, 0x5a]])),
+      },
+    ];
+
+    expect(findMissingGlyphs('ZAŻÓŁĆ €', fonts)).toEqual([
+      'Ż',
+      'Ó',
+      'Ł',
+      'Ć',
+      '€',
+    ]);
+    expect(findMissingGlyphs('ABC', fonts)).toEqual([]);
+    // fonts with an unknown character map are not checked
+    expect(
+      findMissingGlyphs('ł', [{ ...fonts[0], codePoints: undefined }]),
+    ).toEqual([]);
+  });
+
+  it('should take the visible text of a container', () => {
+    const text = getHtmlText(
+      '<style>.a { color: red; }</style><div class="a">Hi&nbsp;there</div>',
+    );
+
+    expect(text.trim().split(/\s+/)).toEqual(['Hi', 'there']);
+  });
+
+  it('should request one weight and style from Google Fonts', () => {
+    expect(
+      getGoogleFontCssUrl({
+        family: 'Body',
+        google: 'Open Sans',
+        weight: '700',
+        style: 'italic',
+      }),
+    ).toBe(
+      'https://fonts.googleapis.com/css2?family=Open+Sans:ital,wght@1,700',
+    );
+  });
+
+  it('should embed local fonts as @font-face rules', async () => {
+    const path = resolve(dir, 'brand.ttf');
+    writeFileSync(path, makeFont([[0x41, 0x5a]]));
+
+    const fonts = await resolveFonts(
+      [{ family: 'Brand', weight: '700', style: 'normal', path }],
+      dir,
+    );
+    const css = await makeFontFaceCss(fonts);
+
+    expect(fonts[0].codePoints?.size).toBe(26);
+    expect(css).toContain('font-family: "Brand"; font-weight: 700;');
+    expect(css).toContain('src: url(data:font/ttf;base64,');
+    expect(css).not.toContain('local(');
+
+    await expect(
+      resolveFonts(
+        [
+          {
+            family: 'Missing',
+            weight: '400',
+            style: 'normal',
+            path: resolve(dir, 'missing.ttf'),
+          },
+        ],
+        dir,
+      ),
+    ).rejects.toThrow('Font file of "Missing" not found');
+  });
+});
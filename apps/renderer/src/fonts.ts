@@ -0,0 +1,309 @@
+import { createHash } from 'crypto';
+import { existsSync } from 'fs';
+import { mkdir, readFile, rename, writeFile } from 'fs/promises';
+import { dirname, extname, resolve } from 'path';
+import { Font } from './type';
+import { recordCacheHit, recordCacheMiss } from './cache-stats';
+
+// A font file ready to be embedded
+export type ResolvedFont = Font & {
+  file: string; // local file or the downloaded copy in cache/fonts
+  codePoints?: Set<number>; // characters with a glyph, unknown for WOFF/WOFF2
+};
+
+const FONT_FORMATS: Record<string, { format: string; mime: string }> = {
+  '.ttf': { format: 'truetype', mime: 'font/ttf' },
+  '.otf': { format: 'opentype', mime: 'font/otf' },
+  '.woff': { format: 'woff', mime: 'font/woff' },
+  '.woff2': { format: 'woff2', mime: 'font/woff2' },
+};
+
+export function isSupportedFontFile(path: string): boolean {
+  return extname(path).toLowerCase() in FONT_FORMATS;
+}
+
+/**
+ * URL of the Google Fonts stylesheet of one weight/style of a family.
+ * Without a browser user agent the stylesheet points at TrueType files.
+ */
+export function getGoogleFontCssUrl(font: Font): string {
+  const family = encodeURIComponent(font.google ?? font.family).replace(
+    /%20/g,
+    '+',
+  );
+  const italic = font.style === 'italic' ? 1 : 0;
+  return `https://fonts.googleapis.com/css2?family=${family}:ital,wght@${italic},${font.weight}`;
+}
+
+/**
+ * Path of a downloaded Google font in cache/fonts of the project
+ */
+export function getGoogleFontCachePath(font: Font, projectDir: string): string {
+  const key = createHash('sha256')
+    .update(
+      JSON.stringify({
+        google: font.google,
+        weight: font.weight,
+        style: font.style,
+      }),
+    )
+    .digest('hex')
+    .substring(0, 16);
+  return resolve(projectDir, 'cache', 'fonts', `${key}.ttf`);
+}
+
+/**
+ * Downloads a Google font to cache/fonts once, later renders (and other
+ * machines sharing the cache) use the same file
+ */
+async function downloadGoogleFont(
+  font: Font,
+  projectDir: string,
+): Promise<string> {
+  const path = getGoogleFontCachePath(font, projectDir);
+  const cacheDir = dirname(path);
+
+  if (existsSync(path)) {
+    recordCacheHit('fonts');
+    return path;
+  }
+  recordCacheMiss('fonts');
+
+  const description = `Google font "${font.google}" (${font.weight} ${font.style})`;
+  console.log(`🔤 Downloading ${description}...`);
+
+  const cssResponse = await fetch(getGoogleFontCssUrl(font));
+  if (!cssResponse.ok) {
+    throw new Error(
+      `${description} could not be downloaded: ${cssResponse.status} ${cssResponse.statusText}`,
+    );
+  }
+  const url = (await cssResponse.text()).match(/url\(([^)]+)\)/)?.[1];
+  if (!url) {
+    throw new Error(`${description} could not be downloaded: no font file`);
+  }
+
+  const fontResponse = await fetch(url);
+  if (!fontResponse.ok) {
+    throw new Error(
+      `${description} could not be downloaded: ${fontResponse.status} ${fontResponse.statusText}`,
+    );
+  }
+
+  // Written under a temporary name, so an interrupted download
+  // is never picked up from the cache
+  await mkdir(cacheDir, { recursive: true });
+  const partialPath = `${path}.partial`;
+  await writeFile(partialPath, Buffer.from(await fontResponse.arrayBuffer()));
+  await rename(partialPath, path);
+
+  return path;
+}
+
+/**
+ * Resolves the fonts to files: local fonts as they are, Google fonts are
+ * downloaded to cache/fonts (or taken from there)
+ * @throws Error if a local font file is missing or a download fails
+ */
+export async function resolveFonts(
+  fonts: Font[],
+  projectDir: string,
+): Promise<ResolvedFont[]> {
+  const resolved: ResolvedFont[] = [];
+
+  for (const font of fonts) {
+    let file: string;
+    if (font.google) {
+      file = await downloadGoogleFont(font, projectDir);
+    } else if (font.path && existsSync(font.path)) {
+      file = font.path;
+    } else {
+      throw new Error(
+        `Font file of "${font.family}" not found: ${font.path ?? '(no src)'}`,
+      );
+    }
+
+    resolved.push({
+      ...font,
+      file,
+      codePoints: readCharacterMap(await readFile(file)),
+    });
+  }
+
+  return resolved;
+}
+
+/**
+ * Makes the @font-face rules of the fonts. The files are embedded as data
+ * URLs: the page doesn't load anything, and a family never resolves to a
+ * font installed on the machine (no local()), so text renders the same
+ * everywhere. Since the rules are a part of the page CSS, changing a font
+ * file invalidates the cached containers.
+ */
+export async function makeFontFaceCss(
+  fonts: ResolvedFont[],
+): Promise<string> {
+  const rules: string[] = [];
+
+  for (const font of fonts) {
+    const { format, mime } =
+      FONT_FORMATS[extname(font.file).toLowerCase()] ?? FONT_FORMATS['.ttf'];
+    const data = (await readFile(font.file)).toString('base64');
+    rules.push(
+      `@font-face { font-family: "${font.family}"; font-weight: ${font.weight}; font-style: ${font.style}; font-display: block; src: url(data:${mime};base64,${data}) format('${format}'); }`,
+    );
+  }
+
+  return rules.join('\n');
+}
+
+/**
+ * Reads the characters a TrueType/OpenType font has glyphs for, from its
+ * cmap table (Unicode subtables of format 4 or 12)
+ * @returns undefined for other files (WOFF, WOFF2) and unsupported tables
+ */
+export function readCharacterMap(data: Buffer): Set<number> | undefined {
+  if (data.length < 12) {
+    return undefined;
+  }
+  const signature = data.readUInt32BE(0);
+  // 0x00010000 and "true" (TrueType), "OTTO" (OpenType with CFF outlines)
+  if (
+    signature !== 0x00010000 &&
+    signature !== 0x74727565 &&
+    signature !== 0x4f54544f
+  ) {
+    return undefined;
+  }
+
+  let cmap = -1;
+  const numTables = data.readUInt16BE(4);
+  for (let i = 0; i < numTables; i++) {
+    const record = 12 + i * 16;
+    if (data.toString('latin1', record, record + 4) === 'cmap') {
+      cmap = data.readUInt32BE(record + 8);
+    }
+  }
+  if (cmap < 0) {
+    return undefined;
+  }
+
+  // Prefer the full Unicode subtable (format 12) to the BMP one (format 4)
+  let subtable = -1;
+  let subtableFormat = 0;
+  const numSubtables = data.readUInt16BE(cmap + 2);
+  for (let i = 0; i < numSubtables; i++) {
+    const record = cmap + 4 + i * 8;
+    const platformId = data.readUInt16BE(record);
+    const encodingId = data.readUInt16BE(record + 2);
+    const offset = cmap + data.readUInt32BE(record + 4);
+    const format = data.readUInt16BE(offset);
+
+    const isUnicode =
+      platformId === 0 ||
+      (platformId === 3 && (encodingId === 1 || encodingId === 10));
+    if (
+      isUnicode &&
+      (format === 4 || format === 12) &&
+      format > subtableFormat
+    ) {
+      subtable = offset;
+      subtableFormat = format;
+    }
+  }
+  if (subtable < 0) {
+    return undefined;
+  }
+
+  const codePoints = new Set<number>();
+
+  if (subtableFormat === 12) {
+    const numGroups = data.readUInt32BE(subtable + 12);
+    for (let i = 0; i < numGroups; i++) {
+      const group = subtable + 16 + i * 12;
+      const start = data.readUInt32BE(group);
+      const end = data.readUInt32BE(group + 4);
+      for (let codePoint = start; codePoint <= end; codePoint++) {
+        codePoints.add(codePoint);
+      }
+    }
+    return codePoints;
+  }
+
+  const segCount = data.readUInt16BE(subtable + 6) / 2;
+  const endCodes = subtable + 14;
+  const startCodes = endCodes + segCount * 2 + 2;
+  const idDeltas = startCodes + segCount * 2;
+  const idRangeOffsets = idDeltas + segCount * 2;
+
+  for (let segment = 0; segment < segCount; segment++) {
+    const start = data.readUInt16BE(startCodes + segment * 2);
+    const end = data.readUInt16BE(endCodes + segment * 2);
+    const delta = data.readUInt16BE(idDeltas + segment * 2);
+    const rangeOffsetAddress = idRangeOffsets + segment * 2;
+    const rangeOffset = data.readUInt16BE(rangeOffsetAddress);
+
+    for (let codePoint = start; codePoint <= end; codePoint++) {
+      if (codePoint === 0xffff) {
+        break;
+      }
+      let glyph: number;
+      if (rangeOffset === 0) {
+        glyph = (codePoint + delta) & 0xffff;
+      } else {
+        glyph = data.readUInt16BE(
+          rangeOffsetAddress + rangeOffset + (codePoint - start) * 2,
+        );
+        if (glyph !== 0) {
+          glyph = (glyph + delta) & 0xffff;
+        }
+      }
+      // glyph 0 is .notdef, the "missing glyph" box
+      if (glyph !== 0) {
+        codePoints.add(codePoint);
+      }
+    }
+  }
+
+  return codePoints;
+}
+
+/**
+ * Visible text of an HTML fragment (markup, styles and entities removed)
+ */
+export function getHtmlText(html: string): string {
+  return html
+    .replace(/<(style|script)[\s\S]*?<\/\1>/gi, ' ')
+    .replace(/<[^>]*>/g, ' ')
+    .replace(/&[a-z0-9#]+;/gi, ' ');
+}
+
+/**
+ * Characters of the text that none of the fonts has a glyph for, they are
+ * drawn with a fallback font of the machine. Fonts with an unknown
+ * character map (WOFF/WOFF2) are not checked.
+ * @returns Unique missing characters in order of appearance
+ */
+export function findMissingGlyphs(
+  text: string,
+  fonts: ResolvedFont[],
+): string[] {
+  const maps = fonts
+    .map((font) => font.codePoints)
+    .filter((codePoints): codePoints is Set<number> => !!codePoints);
+  if (maps.length === 0) {
+    return [];
+  }
+
+  const missing: string[] = [];
+  for (const character of text) {
+    if (/\s/.test(character) || missing.includes(character)) {
+      continue;
+    }
+    const codePoint = character.codePointAt(0)!;
+    if (!maps.some((codePoints) => codePoints.has(codePoint))) {
+      missing.push(character);
+    }
+  }
+  return missing;
+}
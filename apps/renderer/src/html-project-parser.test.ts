@@ -0,0 +1,978 @@
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import type { Element } from 'domhandler';
+import { HTMLParser } from './html-parser';
+import { getDefaultFFmpegArgs } from './ffmpeg';
+import { formatRenderDate } from './build-info';
+import { getRootFontSize } from './container-renderer';
+import {
+  HTMLProjectParser,
+  ParserOptions,
+  normalizeRotation,
+} from './html-project-parser';
+import { Asset } from './type';
+
+describe('HTMLProjectParser', () => {
+  // Helper to parse a project without assets (no ffprobe calls are made)
+  const parseProject = async (html: string, options: ParserOptions = {}) => {
+    const parsed = new HTMLParser().parse(`<title>Test</title>${html}`);
+    const parser = new HTMLProjectParser(
+      parsed,
+      '/tmp/test/project.html',
+      options,
+    );
+    return parser.parse();
+  };
+
+  // Source assets by name, instead of probing files with ffprobe
+  const SOURCES: Record<string, Asset> = {
+    beach: {
+      name: 'beach',
+      path: '/tmp/test/beach.mp4',
+      type: 'video',
+      duration: 20000,
+      width: 1920,
+      height: 1080,
+      rotation: 0,
+      hasVideo: true,
+      hasAudio: true,
+    },
+    cover: {
+      name: 'cover',
+      path: '/tmp/test/cover.jpg',
+      type: 'image',
+      duration: 0,
+      width: 1920,
+      height: 1080,
+      rotation: 0,
+      hasVideo: true,
+      hasAudio: false,
+    },
+  };
+
+  const parseWithSources = (
+    subclips: string,
+    html = '',
+    options: ParserOptions = {},
+  ) => {
+    vi.spyOn(
+      HTMLProjectParser.prototype as any,
+      'extractAssetFromElement',
+    ).mockImplementation(async (element: unknown) => ({
+      ...SOURCES[(element as Element).attribs['data-name']],
+    }));
+    return parseProject(
+      `
+        <assets>
+          <asset data-name="beach" data-path="./beach.mp4" />
+          <asset data-name="cover" data-path="./cover.jpg" />
+          ${subclips}
+        </assets>
+        <outputs>
+          <output name="youtube" resolution="1920x1080" fps="30" />
+        </outputs>
+        ${html}
+      `,
+      options,
+    );
+  };
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  describe('Sequence inheritance', () => {
+    it('should inherit sequence properties into fragments', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence class="slideshow">
+            <fragment id="a" />
+            <fragment id="b" />
+          </sequence>
+        </project>
+        <style>
+          .slideshow {
+            -duration: 3s;
+            -transition-start: fade-in 500ms;
+            -sound: off;
+          }
+        </style>
+      `);
+
+      const [sequence] = project.getSequenceDefinitions();
+      expect(sequence.fragments).toHaveLength(2);
+      for (const fragment of sequence.fragments) {
+        expect(fragment.duration).toBe(3000);
+        expect(fragment.transitionIn).toBe('fade-in');
+        expect(fragment.transitionInDuration).toBe(500);
+        expect(fragment.sound).toBe('off');
+      }
+    });
+
+    it('should let fragment properties override inherited ones', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence style="-duration: 3s; -transition-end: fade-out 1s;">
+            <fragment id="a" class="long" />
+            <fragment id="b" />
+          </sequence>
+        </project>
+        <style>
+          .long { -duration: 8s; }
+        </style>
+      `);
+
+      const [sequence] = project.getSequenceDefinitions();
+      expect(sequence.fragments[0].duration).toBe(8000);
+      expect(sequence.fragments[0].transitionOut).toBe('fade-out');
+      expect(sequence.fragments[1].duration).toBe(3000);
+    });
+
+    it('should not inherit non-inheritable properties', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence style="-asset: clip; -offset-start: 2s; display: none;">
+            <fragment id="a" style="-duration: 1s;" />
+          </sequence>
+        </project>
+      `);
+
+      const [fragment] = project.getSequenceDefinitions()[0].fragments;
+      expect(fragment.assetName).toBe('');
+      expect(fragment.overlayLeft).toBe(0);
+      expect(fragment.enabled).toBe(true);
+    });
+  });
+
+  describe('Gaps', () => {
+    it('should parse <gap> elements in document order', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence>
+            <fragment id="a" style="-duration: 1s;" />
+            <gap id="pause" duration="2s" />
+            <fragment id="b" style="-duration: 1s;" />
+          </sequence>
+        </project>
+      `);
+
+      const fragments = project.getSequenceDefinitions()[0].fragments;
+      expect(fragments.map((f) => f.id)).toEqual(['a', 'pause', 'b']);
+      expect(fragments[1].gap).toEqual({ color: '#00000000' });
+      expect(fragments[1].assetName).toBe('');
+      expect(fragments[1].duration).toBe(2000);
+      expect(fragments[0].gap).toBeUndefined();
+    });
+
+    it('should treat unitless gap duration as seconds and keep the color', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence>
+            <gap duration="3" color="white" />
+            <gap duration="500ms" color="transparent" />
+          </sequence>
+        </project>
+      `);
+
+      const [white, transparent] = project.getSequenceDefinitions()[0].fragments;
+      expect(white.duration).toBe(3000);
+      expect(white.gap).toEqual({ color: 'white' });
+      expect(transparent.duration).toBe(500);
+      expect(transparent.gap).toEqual({ color: '#00000000' });
+    });
+
+    it('should expand short hex gap colors', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence>
+            <gap duration="1s" color="#f80" />
+            <gap duration="1s" color="#10204080" />
+          </sequence>
+        </project>
+      `);
+
+      const [short, long] = project.getSequenceDefinitions()[0].fragments;
+      expect(short.gap).toEqual({ color: '#ff8800' });
+      expect(long.gap).toEqual({ color: '#10204080' });
+    });
+
+    it('should report gap colors FFmpeg cannot take', async () => {
+      const error = await parseProject(
+        `
+          <project>
+            <sequence>
+              <gap id="a" duration="1s" color="rgba(0,0,0,.5)" />
+              <gap id="b" duration="1s" color='bl"ack' />
+            </sequence>
+          </project>
+        `,
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      const message = (error as Error).message;
+      expect(message).toContain('Invalid color "rgba(0,0,0,.5)" of gap "a"');
+      expect(message).toContain('Invalid color "bl"ack" of gap "b"');
+    });
+  });
+
+  describe('Freeze frame', () => {
+    it('should parse -freeze and -freeze-at', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence>
+            <fragment id="a" style="-duration: 5s; -freeze: last 2s;" />
+            <fragment id="b" style="-duration: 5s; -freeze: FIRST 500ms;" />
+            <fragment id="c" style="-duration: 5s; -freeze-at: 00:03.5;" />
+            <fragment id="d" style="-freeze-at: 01:02:03 4s;" />
+            <fragment id="e" style="-duration: 5s; -freeze: middle 2s;" />
+          </sequence>
+        </project>
+      `);
+
+      const [a, b, c, d, e] = project.getSequenceDefinitions()[0].fragments;
+      expect(a.freeze).toEqual({ mode: 'last', duration: 2000, time: 0 });
+      expect(a.duration).toBe(5000);
+      expect(b.freeze).toEqual({ mode: 'first', duration: 500, time: 0 });
+      expect(c.freeze).toEqual({ mode: 'at', duration: 0, time: 3500 });
+      expect(d.freeze).toEqual({ mode: 'at', duration: 4000, time: 3723000 });
+      expect(d.duration).toBe(4000);
+      expect(e.freeze).toBeUndefined();
+    });
+  });
+
+  describe('Outputs', () => {
+    it('should parse audio-only output formats', async () => {
+      const project = await parseProject(`
+        <outputs>
+          <output name="youtube" resolution="1920x1080" fps="30" />
+          <output name="podcast" format="mp3" />
+          <output name="lossless" format="FLAC" path="./out/audio.flac" />
+          <output name="unknown" format="ogg" />
+        </outputs>
+      `);
+
+      const outputs = project.getOutputs();
+      expect(outputs.get('youtube')!.format).toBeUndefined();
+      expect(outputs.get('youtube')!.path).toBe('/tmp/test/output/youtube.mp4');
+      expect(outputs.get('podcast')!.format).toBe('mp3');
+      expect(outputs.get('podcast')!.path).toBe('/tmp/test/output/podcast.mp3');
+      expect(outputs.get('lossless')!.format).toBe('flac');
+      expect(outputs.get('lossless')!.path).toBe('/tmp/test/out/audio.flac');
+      expect(outputs.get('unknown')!.format).toBeUndefined();
+    });
+  });
+
+  describe('Assertions', () => {
+    it('should parse text, pixel and duration assertions', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence id="main">
+            <fragment id="a" style="-duration: 2s;" />
+          </sequence>
+        </project>
+        <tests>
+          <assert id="welcome" at="00:05" sequence="main" contains-text="Welcome" />
+          <assert at="1.5s" pixel="10,20" color="#f00" color-tolerance="8" />
+          <assert duration="00:30" />
+        </tests>
+      `);
+
+      const [text, pixel, duration] = project.getAssertions();
+      expect(text).toEqual({
+        name: 'welcome',
+        at: 5000,
+        sequence: 'main',
+        containsText: 'Welcome',
+      });
+      expect(pixel.name).toBe('assert #2');
+      expect(pixel.at).toBe(1500);
+      expect(pixel.pixel).toEqual({
+        x: 10,
+        y: 20,
+        color: { r: 255, g: 0, b: 0 },
+        tolerance: 8,
+      });
+      expect(duration.duration).toEqual({ value: 30000, tolerance: 100 });
+    });
+
+    it('should report assertions that need a time', async () => {
+      const error = await parseProject(
+        `
+          <project>
+            <sequence>
+              <fragment id="a" style="-duration: 2s;" />
+            </sequence>
+          </project>
+          <assert contains-text="Welcome" />
+        `,
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      expect((error as Error).message).toContain(
+        'Assertion "assert #1" needs the "at" attribute',
+      );
+    });
+  });
+
+  describe('Parse modes', () => {
+    const source = `
+      <project>
+        <sequence>
+          <fragment id="a" data-asset="missing" style="-durration: 3s;" />
+          <gap id="b" />
+          <fragment id="c">
+            <container><marquee>Hello</marquee></container>
+          </fragment>
+        </sequence>
+        <soundtrack />
+      </project>
+    `;
+
+    it('should ignore unknown elements and properties by default', async () => {
+      await expect(parseProject(source)).resolves.toBeDefined();
+    });
+
+    it('should report all problems at once in strict mode', async () => {
+      const error = await parseProject(source, { mode: 'strict' }).catch(
+        (e: Error) => e,
+      );
+
+      expect(error).toBeInstanceOf(Error);
+      const message = (error as Error).message;
+      expect(message).toContain('4 problem(s)');
+      expect(message).toContain('Unknown element <soundtrack>');
+      expect(message).toContain(
+        'Unknown property "-durration" on <fragment id="a">',
+      );
+      expect(message).toContain(
+        '<fragment id="a"> references unknown asset "missing"',
+      );
+      expect(message).toContain('<gap id="b"> is missing the duration attribute');
+      // container content is free-form HTML
+      expect(message).not.toContain('marquee');
+    });
+
+    it('should accept a valid project in strict mode', async () => {
+      const project = await parseProject(
+        `
+          <project>
+            <sequence class="defaults">
+              <fragment id="a" style="-freeze: last 1s;">
+                <container><div>Title</div></container>
+              </fragment>
+              <gap duration="1s" />
+            </sequence>
+          </project>
+          <style>
+            .defaults { -duration: 2s; -transition-start: fade-in 500ms; }
+          </style>
+        `,
+        { mode: 'strict' },
+      );
+
+      expect(project.getSequenceDefinitions()[0].fragments).toHaveLength(2);
+    });
+  });
+
+  describe('Markers', () => {
+    it('should point markers at the fragment that follows them', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence id="main">
+            <marker name="hook" />
+            <fragment id="a" />
+            <fragment id="b" />
+            <marker name="cta" />
+            <gap duration="1s" />
+            <marker name="end" />
+          </sequence>
+        </project>
+      `);
+
+      const [sequence] = project.getSequenceDefinitions();
+      expect(sequence.fragments).toHaveLength(3);
+      expect(sequence.markers).toEqual([
+        { name: 'hook', fragmentIndex: 0 },
+        { name: 'cta', fragmentIndex: 2 },
+        { name: 'end', fragmentIndex: 3 },
+      ]);
+    });
+
+    it('should report unnamed and duplicate markers', async () => {
+      const error = await parseProject(
+        `
+          <project>
+            <sequence>
+              <marker name="hook" />
+              <gap duration="1s" />
+              <marker />
+            </sequence>
+            <sequence>
+              <marker name="hook" />
+              <gap duration="1s" />
+            </sequence>
+          </project>
+        `,
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      expect(error).toBeInstanceOf(Error);
+      const message = (error as Error).message;
+      expect(message).toContain('<marker> is missing the name attribute');
+      expect(message).toContain('Duplicate marker name "hook"');
+    });
+  });
+
+  describe('Orientation', () => {
+    it('should normalize rotation angles', () => {
+      expect(normalizeRotation(0)).toBe(0);
+      expect(normalizeRotation(90)).toBe(90);
+      expect(normalizeRotation(-90)).toBe(270);
+      expect(normalizeRotation(-180)).toBe(180);
+      expect(normalizeRotation(450)).toBe(90);
+    });
+
+    it('should parse -orient and inherit it from the sequence', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence class="phone">
+            <fragment id="a" />
+            <fragment id="b" style="-orient: -90deg;" />
+            <fragment id="c" style="-orient: auto;" />
+          </sequence>
+        </project>
+        <style>
+          .phone { -orient: 90; }
+        </style>
+      `);
+
+      const [a, b, c] = project.getSequenceDefinitions()[0].fragments;
+      expect(a.orientation).toBe(90);
+      expect(b.orientation).toBe(270);
+      expect(c.orientation).toBeUndefined();
+    });
+
+    it('should report invalid -orient values', async () => {
+      const error = await parseProject(
+        `
+          <project>
+            <sequence>
+              <fragment id="a" style="-orient: 45;" />
+            </sequence>
+          </project>
+        `,
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      expect(error).toBeInstanceOf(Error);
+      expect((error as Error).message).toContain('Invalid -orient value "45"');
+    });
+  });
+
+  describe('Alpha outputs', () => {
+    it('should parse alpha outputs and pick alpha codecs', async () => {
+      const project = await parseProject(`
+        <outputs>
+          <output name="overlay" alpha />
+          <output name="web" path="./output/overlay.webm" alpha="true" />
+          <output name="youtube" />
+        </outputs>
+      `);
+
+      const overlay = project.getOutput('overlay')!;
+      expect(overlay.alpha).toBe(true);
+      expect(overlay.path).toBe('/tmp/test/output/overlay.mov');
+      expect(getDefaultFFmpegArgs(overlay)).toContain(
+        'prores_ks -profile:v 4444',
+      );
+
+      const web = project.getOutput('web')!;
+      expect(web.alpha).toBe(true);
+      expect(getDefaultFFmpegArgs(web)).toContain('-pix_fmt yuva420p');
+
+      expect(project.getOutput('youtube')!.alpha).toBeUndefined();
+    });
+
+    it('should report alpha in containers that cannot keep it', async () => {
+      const error = await parseProject(
+        `
+          <outputs>
+            <output name="youtube" path="./output/video.mp4" alpha />
+          </outputs>
+        `,
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      expect(error).toBeInstanceOf(Error);
+      expect((error as Error).message).toContain(
+        `Output "youtube" can't keep alpha in a .mp4 file`,
+      );
+    });
+  });
+
+  describe('Output base size', () => {
+    it('should scale rem of containers with the output', async () => {
+      const project = await parseProject(`
+        <outputs base-size="1920x1080">
+          <output name="master" resolution="3840x2160" />
+          <output name="proxy" resolution="1280x720" />
+          <output name="vertical" resolution="1080x1920" />
+          <output name="mobile" resolution="1080x1920" base-size="1080x1920" />
+        </outputs>
+      `);
+
+      expect(project.getOutput('master')!.baseSize).toEqual({
+        width: 1920,
+        height: 1080,
+      });
+      expect(getRootFontSize(project.getOutput('master')!)).toBe(32);
+      expect(getRootFontSize(project.getOutput('proxy')!)).toBe(10.67);
+      expect(getRootFontSize(project.getOutput('vertical')!)).toBe(9);
+      expect(getRootFontSize(project.getOutput('mobile')!)).toBe(16);
+    });
+
+    it('should keep 16px without a base size', async () => {
+      const project = await parseProject(`
+        <outputs>
+          <output name="youtube" resolution="3840x2160" />
+        </outputs>
+      `);
+
+      const output = project.getOutput('youtube')!;
+      expect(output.baseSize).toBeUndefined();
+      expect(getRootFontSize(output)).toBe(16);
+    });
+
+    it('should report invalid base sizes', async () => {
+      const error = await parseProject(
+        `
+          <outputs>
+            <output name="youtube" base-size="1080p" />
+          </outputs>
+        `,
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      expect((error as Error).message).toContain(
+        'Invalid base size "1080p" of output "youtube"',
+      );
+    });
+  });
+
+  describe('Build info', () => {
+    it('should stamp containers and app parameters', async () => {
+      const project = await parseProject(`
+        <version>1.4.0</version>
+        <project>
+          <sequence>
+            <fragment id="a" style="-duration: 2s;">
+              <container id="stamp">
+                <div>v{{ .ProjectVersion }} ({{.GitCommit}}) {{ .RenderDate }}</div>
+              </container>
+            </fragment>
+            <fragment id="b" style="-duration: 2s;">
+              <app src="./apps/slate" data-parameters='{"label": "{{ .ProjectVersion }}"}' />
+            </fragment>
+          </sequence>
+        </project>
+      `);
+
+      const [a, b] = project.getSequenceDefinitions()[0].fragments;
+      // the project directory is not in a git repository
+      expect(a.container?.htmlContent).toMatch(
+        /v1\.4\.0 \(unknown\) \d{4}-\d{2}-\d{2} \d{2}:\d{2}/,
+      );
+      expect(b.app?.parameters.label).toBe('1.4.0');
+    });
+
+    it('should stamp the title and upload descriptions', async () => {
+      const project = await parseProject(`
+        <version>2.0</version>
+        <uploads>
+          <youtube name="review" data-output-name="youtube">
+            <title>Demo v{{ .ProjectVersion }}</title>
+            <pre>Review cut {{ .ProjectVersion }}</pre>
+          </youtube>
+        </uploads>
+      `);
+
+      const upload = project.getUpload('review');
+      expect(upload?.title).toBe('Demo v2.0');
+      expect(upload?.description).toBe('Review cut 2.0');
+    });
+
+    it('should report unknown variables', async () => {
+      const error = await parseProject(
+        `
+          <project>
+            <sequence>
+              <fragment id="a" style="-duration: 2s;">
+                <container id="stamp"><div>{{ .BuildNumber }}</div></container>
+              </fragment>
+            </sequence>
+          </project>
+        `,
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      expect((error as Error).message).toContain(
+        'Unknown variable {{ .BuildNumber }} in container "stamp"',
+      );
+    });
+
+    it('should format the render date', () => {
+      expect(formatRenderDate(new Date(2025, 2, 4, 9, 5))).toBe(
+        '2025-03-04 09:05',
+      );
+    });
+  });
+
+  describe('Fonts', () => {
+    it('should parse the fonts section', async () => {
+      const project = await parseProject(`
+        <fonts>
+          <font family="Body" google="Open Sans" />
+          <font family="Headline" google="Playfair Display" weight="700" style="italic" />
+        </fonts>
+      `);
+
+      expect(project.getFonts()).toEqual([
+        { family: 'Body', weight: '400', style: 'normal', google: 'Open Sans' },
+        {
+          family: 'Headline',
+          weight: '700',
+          style: 'italic',
+          google: 'Playfair Display',
+        },
+      ]);
+    });
+
+    it('should report invalid fonts', async () => {
+      const error = await parseProject(
+        `
+          <fonts>
+            <font google="Inter" />
+            <font family="Brand" />
+            <font family="Logo" src="./fonts/logo.svg" />
+            <font family="Title" src="./fonts/missing.ttf" weight="bold" />
+          </fonts>
+        `,
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      const message = (error as Error).message;
+      expect(message).toContain('<font> is missing the family attribute');
+      expect(message).toContain(
+        '<font family="Brand"> needs either a src (local file) or a google',
+      );
+      expect(message).toContain('Unsupported font file ./fonts/logo.svg');
+      expect(message).toContain(
+        'Invalid weight "bold" of <font family="Title">',
+      );
+    });
+  });
+
+  describe('Ken Burns shorthand', () => {
+    it('should expand zoom effects', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence>
+            <fragment id="a" style="-ken-burns: zoom-in 1.0 1.15 center;" />
+            <fragment id="b" style="-ken-burns: zoom-out 1.3 1.1 top-left ease-out;" />
+          </sequence>
+        </project>
+      `);
+
+      const [a, b] = project.getSequenceDefinitions()[0].fragments;
+      expect(a).toMatchObject({
+        objectFit: 'ken-burns',
+        objectFitKenBurns: 'zoom-in',
+        objectFitKenBurnsZoom: 15,
+        objectFitKenBurnsEasing: 'linear',
+        objectFitKenBurnsFocalX: 50,
+        objectFitKenBurnsFocalY: 50,
+      });
+      expect(a.objectFitKenBurnsZoomBase).toBeUndefined();
+      expect(b).toMatchObject({
+        objectFit: 'ken-burns',
+        objectFitKenBurns: 'zoom-out',
+        objectFitKenBurnsZoom: 30,
+        objectFitKenBurnsZoomBase: 10,
+        objectFitKenBurnsEasing: 'ease-out',
+        objectFitKenBurnsFocalX: 0,
+        objectFitKenBurnsFocalY: 0,
+      });
+    });
+
+    it('should expand pans and inherit from the sequence', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence class="slideshow">
+            <fragment id="a" />
+            <fragment id="b" style="-ken-burns: pan-top 1.25;" />
+          </sequence>
+        </project>
+        <style>
+          .slideshow { -ken-burns: pan-left; }
+        </style>
+      `);
+
+      const [a, b] = project.getSequenceDefinitions()[0].fragments;
+      expect(a).toMatchObject({
+        objectFit: 'ken-burns',
+        objectFitKenBurns: 'pan-left',
+        objectFitKenBurnsZoom: 15,
+        objectFitKenBurnsPanStartX: 100,
+        objectFitKenBurnsPanEndX: 0,
+      });
+      expect(b).toMatchObject({
+        objectFitKenBurns: 'pan-top',
+        objectFitKenBurnsZoom: 25,
+        objectFitKenBurnsPanStartY: 100,
+        objectFitKenBurnsPanEndY: 0,
+      });
+    });
+
+    it('should report invalid values', async () => {
+      const error = await parseProject(
+        `
+          <project>
+            <sequence>
+              <fragment id="a" style="-ken-burns: zoom-in 1.2 1.0;" />
+              <fragment id="b" style="-ken-burns: zoom-in 1.0 1.2 middle;" />
+              <fragment id="c" style="-ken-burns: pan-right 1.0;" />
+            </sequence>
+          </project>
+        `,
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      const message = (error as Error).message;
+      expect(message).toContain(
+        'fragment "a": zoom-in needs a larger <to> scale than <from>',
+      );
+      expect(message).toContain('fragment "b": unknown anchor "middle"');
+      expect(message).toContain('fragment "c": a pan needs a scale above 1.0');
+    });
+  });
+
+  describe('A/V offset', () => {
+    it('should play silence if the offset pushes the sound out of the fragment', async () => {
+      const project = await parseWithSources(
+        '',
+        `
+          <project>
+            <sequence>
+              <fragment data-asset="beach" style="-duration: 2s; -av-offset: 3s;" />
+            </sequence>
+          </project>
+        `,
+      );
+
+      const filter = (await project.build('youtube')).render();
+      expect(filter).not.toContain('[0:a]');
+      expect(filter).toContain('atrim=duration=2000ms');
+    });
+
+    it('should parse -av-offset and inherit it from the sequence', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence class="interview">
+            <fragment id="a" />
+            <fragment id="b" style="-av-offset: -0.2s;" />
+            <fragment id="c" style="-av-offset: 0;" />
+          </sequence>
+        </project>
+        <style>
+          .interview { -av-offset: 120ms; }
+        </style>
+      `);
+
+      const fragments = project.getSequenceDefinitions()[0].fragments;
+      expect(fragments.map((fragment) => fragment.avOffset)).toEqual([
+        120, -200, 0,
+      ]);
+    });
+
+    it('should report invalid offsets', async () => {
+      const error = await parseProject(
+        `
+          <project>
+            <sequence>
+              <fragment id="a" style="-av-offset: early;" />
+            </sequence>
+          </project>
+        `,
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      expect((error as Error).message).toContain(
+        'Invalid A/V offset "early" of fragment "a"',
+      );
+    });
+  });
+
+  describe('Scrim', () => {
+    it('should put the scrim under the text of a container', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence>
+            <fragment id="title" style="-scrim: gradient 0.8 top 35%;">
+              <container><h1>Title</h1></container>
+            </fragment>
+          </sequence>
+        </project>
+      `);
+
+      const html =
+        project.getSequenceDefinitions()[0].fragments[0].container!
+          .htmlContent;
+      expect(html).toContain(
+        'linear-gradient(to bottom, color-mix(in srgb, #000000 80%, transparent) 0%, transparent 35%)',
+      );
+      expect(html.indexOf('sts-scrim')).toBeLessThan(html.indexOf('<h1>'));
+    });
+
+    it('should make a container of a fragment with just a scrim', async () => {
+      const project = await parseProject(`
+        <project>
+          <sequence>
+            <fragment id="dim">
+              <scrim type="solid" color="#102040" opacity="0.4" />
+            </fragment>
+          </sequence>
+        </project>
+      `);
+
+      const container =
+        project.getSequenceDefinitions()[0].fragments[0].container!;
+      expect(container.id).toBe('dim_scrim');
+      expect(container.htmlContent).toContain(
+        'background: color-mix(in srgb, #102040 40%, transparent);',
+      );
+    });
+
+    it('should report invalid scrims', async () => {
+      const error = await parseProject(
+        `
+          <project>
+            <sequence>
+              <fragment id="a" style="-scrim: gradient 1.5;" />
+              <fragment id="b" style="-scrim: solid 12px;" />
+            </sequence>
+          </project>
+        `,
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      const message = (error as Error).message;
+      expect(message).toContain(
+        'Invalid scrim opacity "1.5" of fragment "a", expected 0-1',
+      );
+      expect(message).toContain('Invalid scrim value "12px" of fragment "b"');
+    });
+
+    it('should report misspelled scrim values instead of taking them as colors', async () => {
+      const error = await parseProject(
+        `
+          <project>
+            <sequence>
+              <fragment id="a" style="-scrim: gradient bottm;" />
+              <fragment id="b" style="-scrim: solid blak;" />
+            </sequence>
+          </project>
+        `,
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      const message = (error as Error).message;
+      expect(message).toContain('Invalid scrim value "bottm" of fragment "a"');
+      expect(message).toContain('Invalid scrim value "blak" of fragment "b"');
+    });
+
+    it('should accept rgb(), hsl() and named scrim colors', async () => {
+      const project = await parseProject(
+        `
+          <project>
+            <sequence>
+              <fragment id="a" style="-scrim: gradient rgba(0, 0, 0, 0.5);" />
+              <fragment id="b" style="-scrim: solid navy 0.5;" />
+              <fragment id="c" style="-scrim: solid hsl(220deg 40% 20%);" />
+            </sequence>
+          </project>
+        `,
+        { mode: 'strict' },
+      );
+
+      const html = project
+        .getSequenceDefinitions()[0]
+        .fragments.map((fragment) => fragment.container!.htmlContent);
+      expect(html[0]).toContain('rgba(0, 0, 0, 0.5)');
+      expect(html[1]).toContain('color-mix(in srgb, navy 50%, transparent)');
+      expect(html[2]).toContain('hsl(220deg 40% 20%)');
+    });
+  });
+
+  describe('Sub-clips', () => {
+    it('should clamp the range to the source and share its input', async () => {
+      const project = await parseWithSources(`
+        <asset name="wave" from="beach" in="10s" out="30s" />
+        <asset name="crest" from="wave" in="2s" out="5s" />
+      `);
+
+      const assetManager = project.getAssetManager();
+      expect(assetManager.getAssetByName('wave')).toMatchObject({
+        path: '/tmp/test/beach.mp4',
+        duration: 10000,
+        subclip: { from: 'beach', start: 10000, end: 20000 },
+      });
+      // a sub-clip of a sub-clip resolves to the root source
+      expect(assetManager.getAssetByName('crest')).toMatchObject({
+        duration: 3000,
+        subclip: { from: 'beach', start: 12000, end: 15000 },
+      });
+      expect(Array.from(assetManager.getAssetIndexMap())).toEqual([
+        ['beach', 0],
+        ['cover', 1],
+        ['wave', 0],
+        ['crest', 0],
+      ]);
+    });
+
+    it('should report empty ranges and image sources', async () => {
+      const error = await parseWithSources(
+        `
+          <asset name="empty" from="beach" in="5s" out="5s" />
+          <asset name="still" from="cover" in="1s" out="2s" />
+        `,
+        '',
+        { mode: 'strict' },
+      ).catch((e: Error) => e);
+
+      const message = (error as Error).message;
+      expect(message).toContain(
+        'Sub-clip "empty" has an empty range (in=5000ms, out=5000ms)',
+      );
+      expect(message).toContain(
+        'Sub-clip "still" cannot be made of image asset "cover"',
+      );
+    });
+
+    it('should never read past the end of the sub-clip', async () => {
+      const project = await parseWithSources(
+        '<asset name="crest" from="beach" in="12s" out="15s" />',
+        `
+          <project>
+            <sequence>
+              <fragment data-asset="crest" style="-duration: 10s;" />
+            </sequence>
+          </project>
+        `,
+      );
+
+      const filter = (await project.build('youtube')).render();
+      expect(filter).toContain('[0:v]trim=start=12000ms:end=15000ms');
+      expect(filter).toContain('[0:a]atrim=start=12000ms:end=15000ms');
+      expect(filter).not.toMatch(/trim=start=12000ms:end=22000ms/);
+    });
+  });
+});
@@ -1,26 +1,211 @@
 import {
   ParsedHtml,
+  CSSProperties,
   Asset,
   Output,
+  AudioFormat,
   Element,
   ASTNode,
   SequenceDefinition,
   Fragment,
   Container,
   App,
+  Gap,
+  Freeze,
   FFmpegOption,
   Upload,
   AIProvider,
+  ParseMode,
+  Assertion,
+  Marker,
+  Font,
+  Scrim,
 } from './type';
 import { execFile } from 'child_process';
 import { promisify } from 'util';
-import { resolve, dirname } from 'path';
+import { resolve, dirname, extname } from 'path';
 import { existsSync } from 'fs';
 import { Project } from './project';
 import { parseValueLazy, CompiledExpression } from './expression-parser';
+import { parseTimecode } from './time-utils';
+import { isVariableFrameRate } from './conform';
+import {
+  AssetLibrary,
+  isValidLibraryNamespace,
+  loadAssetLibrary,
+  parseAssetRef,
+  resolveLibraryAsset,
+} from './asset-library';
+import { loadWorkspaceConfig } from './workspace-config';
+import { isSupportedFontFile } from './fonts';
+import {
+  DEFAULT_SCRIM,
+  isColorName,
+  isScrimColor,
+  makeScrimHtml,
+} from './scrim';
+import {
+  BuildInfo,
+  BUILD_INFO_VARIABLES,
+  getBuildInfo,
+  hasBuildInfoVariables,
+  interpolateBuildInfo,
+} from './build-info';
 
 const execFileAsync = promisify(execFile);
 
+/**
+ * Output file extensions that can carry an alpha channel
+ * (ProRes 4444 and VP9, see getDefaultFFmpegArgs)
+ */
+const ALPHA_OUTPUT_EXTENSIONS = ['mov', 'webm'];
+
+// Pixel formats with an alpha channel (or a palette that may be transparent)
+const ALPHA_PIXEL_FORMAT = /^(yuva|gbrap|ya\d)|rgba|bgra|argb|abgr|^pal8$/;
+
+// The native VP8/VP9 decoders drop the alpha channel of WebM files
+const ALPHA_DECODERS: Record<string, string> = {
+  vp8: 'libvpx',
+  vp9: 'libvpx-vp9',
+};
+
+/**
+ * Default file extensions of audio-only outputs
+ */
+const AUDIO_FORMAT_EXTENSIONS: Record<AudioFormat, string> = {
+  mp3: 'mp3',
+  aac: 'm4a',
+  flac: 'flac',
+};
+
+// Default scale of -ken-burns: zoom-in to 115%, pans at 115%
+const KEN_BURNS_SCALE = 1.15;
+
+// Focal points (x%, y%) of the -ken-burns anchors
+const KEN_BURNS_ANCHORS: Record<string, [number, number]> = {
+  center: [50, 50],
+  top: [50, 0],
+  bottom: [50, 100],
+  left: [0, 50],
+  right: [100, 50],
+  'top-left': [0, 0],
+  'top-right': [100, 0],
+  'bottom-left': [0, 100],
+  'bottom-right': [100, 100],
+};
+
+/**
+ * Fragment properties that are inherited from the parent <sequence>, like
+ * inherited properties in CSS. A fragment's own value always wins.
+ * Identity, placement and overlay properties (-asset, -offset-*, display, ...)
+ * are intentionally not inherited.
+ */
+export const INHERITED_PROPERTIES = [
+  '-duration',
+  '-trim-start',
+  '-trim-end',
+  '-transition-start',
+  '-transition-end',
+  '-object-fit',
+  '-object-fit-ken-burns',
+  '-ken-burns',
+  '-chromakey',
+  '-sound',
+  'filter',
+  '-orient',
+  '-av-offset',
+];
+
+/**
+ * All properties a fragment understands, anything else is reported in strict mode
+ */
+export const FRAGMENT_PROPERTIES = [
+  ...INHERITED_PROPERTIES,
+  '-asset',
+  'display',
+  '-offset-start',
+  '-offset-end',
+  '-overlay-start-z-index',
+  '-overlay-end-z-index',
+  '-freeze',
+  '-freeze-at',
+  '-scrim',
+];
+
+/**
+ * Elements of the project file, anything else is reported in strict mode
+ */
+const KNOWN_ELEMENTS = new Set([
+  // timeline
+  'project',
+  'sequence',
+  'fragment',
+  'gap',
+  'marker',
+  'container',
+  'app',
+  'scrim',
+  // assets
+  'assets',
+  'asset',
+  'library',
+  'ai',
+  'prompt',
+  'duration',
+  'model',
+  // outputs and encoding
+  'outputs',
+  'output',
+  'ffmpeg',
+  'option',
+  'fonts',
+  'font',
+  // metadata
+  'title',
+  'date',
+  'version',
+  'tag',
+  'style',
+  // tests
+  'tests',
+  'assert',
+  // uploads
+  'uploads',
+  'youtube',
+  's3',
+  'instagram',
+  'public',
+  'unlisted',
+  'private',
+  'made-for-kids',
+  'category',
+  'language',
+  'pre',
+  'thumbnail',
+  'endpoint',
+  'region',
+  'bucket',
+  'path',
+  'acl',
+  'caption',
+  'share-to-feed',
+  'thumb-offset',
+  'cover-url',
+  'video-url',
+  'location',
+]);
+
+/**
+ * Elements with free-form content: their children are not checked
+ * (<ai> also hosts provider elements, e.g. <ai-music-api-ai>)
+ */
+const OPAQUE_ELEMENTS = new Set(['container', 'pre', 'style', 'prompt', 'ai']);
+
+export type ParserOptions = {
+  mode?: ParseMode; // permissive by default
+  libraries?: Record<string, string>; // asset library namespace => path, the workspace config's by default
+};
+
 /**
  * Helper to get attributes as a Map from htmlparser2 element
  */
@@ -34,16 +219,43 @@ function getAttrs(element: Element): Map<string, string> {
   return map;
 }
 
+/**
+ * Normalizes an angle in degrees to 0, 90, 180 or 270
+ */
+export function normalizeRotation(angle: number): number {
+  const rounded = Math.round(angle / 90) * 90;
+  return ((rounded % 360) + 360) % 360;
+}
+
 export class HTMLProjectParser {
   private projectDir: string;
 
+  private problems: string[] = []; // collected in strict mode
+  private markerNames = new Set<string>(); // unique across sequences
+  private libraries?: AssetLibrary[]; // loaded on first <asset ref="...">
+  private failedLibraries = new Set<string>(); // namespaces that failed to load
+  private buildInfo?: BuildInfo; // resolved on the first {{ .Variable }}
+
   constructor(
     private html: ParsedHtml,
     private projectPath: string,
+    private options: ParserOptions = {},
   ) {
     this.projectDir = dirname(projectPath);
   }
 
+  /**
+   * Reports a problem in the project file: collected as an error in strict
+   * mode (the parse fails at the end), printed as a warning otherwise
+   */
+  private report(message: string): void {
+    if (this.options.mode === 'strict') {
+      this.problems.push(message);
+    } else {
+      console.warn(message);
+    }
+  }
+
   /**
    * Extracts AI asset generation requirements without full parsing
    * Used to generate AI assets before full project parsing
@@ -105,13 +317,27 @@ export class HTMLProjectParser {
 
     const outputs = this.processOutputs();
     const ffmpegOptions = this.processFfmpegOptions();
-    const title = this.processTitle();
+    const fonts = this.processFonts();
+    const title = this.interpolateBuildInfo(this.processTitle(), '<title>');
     const date = this.processDate();
     const globalTags = this.processGlobalTags();
     const uploads = this.processUploads(title, globalTags);
     const sequences = this.processSequences(assets);
+    const assertions = this.processAssertions(outputs);
     const cssText = this.html.cssText;
 
+    if (this.options.mode === 'strict') {
+      this.validateElements();
+      this.validateSequences(assets);
+
+      if (this.problems.length > 0) {
+        throw new Error(
+          `Project file has ${this.problems.length} problem(s) (strict mode):\n` +
+            this.problems.map((problem) => `  - ${problem}`).join('\n'),
+        );
+      }
+    }
+
     return new Project(
       sequences,
       assets,
@@ -124,9 +350,95 @@ export class HTMLProjectParser {
       globalTags,
       cssText,
       this.projectPath,
+      assertions,
+      fonts,
     );
   }
 
+  /**
+   * Strict mode: reports elements the parser doesn't know
+   */
+  private validateElements(): void {
+    const traverse = (node: ASTNode) => {
+      if (node.type === 'tag') {
+        const element = node as Element;
+
+        if (!KNOWN_ELEMENTS.has(element.name)) {
+          this.report(`Unknown element <${element.name}>`);
+          return;
+        }
+
+        if (OPAQUE_ELEMENTS.has(element.name)) {
+          return;
+        }
+      }
+
+      if ('children' in node && node.children) {
+        for (const child of node.children) {
+          traverse(child);
+        }
+      }
+    };
+
+    traverse(this.html.ast);
+  }
+
+  /**
+   * Strict mode: reports unknown sequence/fragment properties and
+   * fragments that reference assets that don't exist
+   */
+  private validateSequences(assets: Asset[]): void {
+    for (const sequenceElement of this.findSequenceElements()) {
+      const sequenceStyles = this.html.css.get(sequenceElement) || {};
+      for (const property of Object.keys(sequenceStyles)) {
+        if (!INHERITED_PROPERTIES.includes(property)) {
+          this.report(`Unknown sequence property "${property}"`);
+        }
+      }
+
+      const inheritedStyles = this.getInheritedStyles(sequenceElement);
+
+      for (const element of this.findFragmentChildren(sequenceElement)) {
+        const attrs = getAttrs(element);
+        const label = `<${element.name}${attrs.has('id') ? ` id="${attrs.get('id')}"` : ''}>`;
+        const ownStyles = this.html.css.get(element) || {};
+        const styles = { ...inheritedStyles, ...ownStyles };
+
+        for (const property of Object.keys(ownStyles)) {
+          if (!FRAGMENT_PROPERTIES.includes(property)) {
+            this.report(`Unknown property "${property}" on ${label}`);
+          }
+        }
+
+        if (element.name === 'gap') {
+          if (!attrs.has('duration') && !styles['-duration']) {
+            this.report(`${label} is missing the duration attribute`);
+          }
+          continue;
+        }
+
+        const assetName = attrs.get('data-asset') || styles['-asset'];
+        const hasContent =
+          !!styles['-scrim'] ||
+          element.children.some(
+            (child) =>
+              child.type === 'tag' &&
+              (child.name === 'container' ||
+                child.name === 'app' ||
+                child.name === 'scrim'),
+          );
+
+        if (assetName) {
+          if (!assets.some((asset) => asset.name === assetName)) {
+            this.report(`${label} references unknown asset "${assetName}"`);
+          }
+        } else if (!hasContent) {
+          this.report(`${label} has no asset, container, app or scrim`);
+        }
+      }
+    }
+  }
+
   /**
    * Validates that all asset files exist on the filesystem
    * Throws an error with a list of missing files if any are not found
@@ -163,8 +475,25 @@ export class HTMLProjectParser {
     // Find all elements with class "asset" or data-asset attribute
     const assetElements = this.findAssetElements();
 
+    // Sub-clips (<asset from="...">) are resolved once all sources are known
+    const subclipElements: Element[] = [];
+
     for (const element of assetElements) {
-      const asset = await this.extractAssetFromElement(element);
+      if (getAttrs(element).has('from')) {
+        subclipElements.push(element);
+        continue;
+      }
+
+      const asset = getAttrs(element).has('ref')
+        ? await this.extractLibraryAssetFromElement(element)
+        : await this.extractAssetFromElement(element);
+      if (asset) {
+        result.push(asset);
+      }
+    }
+
+    for (const element of subclipElements) {
+      const asset = this.extractSubclipFromElement(element, result);
       if (asset) {
         result.push(asset);
       }
@@ -173,6 +502,231 @@ export class HTMLProjectParser {
     return result;
   }
 
+  /**
+   * Extracts an asset imported from a shared library
+   * Example: <asset ref="lib:logo" data-author="Studio" />
+   * The library element's attributes apply, the referencing element's own
+   * attributes override them. The asset is named after the library asset,
+   * unless the referencing element has a name of its own.
+   */
+  private async extractLibraryAssetFromElement(
+    element: Element,
+  ): Promise<Asset | null> {
+    const ownAttrs = getAttrs(element);
+    const ref = ownAttrs.get('ref')!;
+    ownAttrs.delete('ref');
+
+    const libraries = this.getLibraries();
+    // The library itself was reported when it failed to load
+    const { namespace } = parseAssetRef(ref);
+    if (namespace && this.failedLibraries.has(namespace)) {
+      return null;
+    }
+
+    let resolved: { library: AssetLibrary; element: Element };
+    try {
+      resolved = resolveLibraryAsset(libraries, ref);
+    } catch (error) {
+      const message = error instanceof Error ? error.message : String(error);
+      const failed = Array.from(this.failedLibraries);
+      this.report(
+        !namespace && failed.length > 0
+          ? `${message}, not loaded: ${failed.join(', ')}`
+          : message,
+      );
+      return null;
+    }
+
+    const { library, element: libraryElement } = resolved;
+    const libraryAttrs = getAttrs(libraryElement);
+    if (libraryAttrs.has('from')) {
+      this.report(
+        `Library asset "${ref}" is a sub-clip, reference its source and define the sub-clip in the project`,
+      );
+      return null;
+    }
+
+    const attrs = new Map([...libraryAttrs, ...ownAttrs]);
+    attrs.set(
+      'data-name',
+      ownAttrs.get('data-name') ||
+        ownAttrs.get('id') ||
+        ownAttrs.get('name') ||
+        parseAssetRef(ref).name,
+    );
+
+    // Paths of the library are relative to the library file
+    if (!ownAttrs.has('data-path') && !ownAttrs.has('src')) {
+      const libraryPath =
+        libraryAttrs.get('data-path') || libraryAttrs.get('src');
+      if (libraryPath) {
+        attrs.delete('src');
+        attrs.set('data-path', resolve(library.dir, libraryPath));
+      }
+    }
+
+    return this.extractAssetFromElement(libraryElement, attrs);
+  }
+
+  /**
+   * Asset libraries in resolution order: the project's <library> elements
+   * in document order, then the libraries of the workspace config.
+   * A project library shadows a workspace library of the same namespace.
+   */
+  private getLibraries(): AssetLibrary[] {
+    if (this.libraries) {
+      return this.libraries;
+    }
+
+    const declarations = new Map<string, string>();
+    for (const element of this.findLibraryElements()) {
+      const attrs = getAttrs(element);
+      const namespace = attrs.get('name')?.trim();
+      const src = attrs.get('src');
+
+      if (!namespace || !src) {
+        this.report('<library> is missing the name or src attribute');
+        continue;
+      }
+      if (!isValidLibraryNamespace(namespace)) {
+        this.report(
+          `Invalid library name "${namespace}", use letters, digits, "-" and "_"`,
+        );
+        continue;
+      }
+      if (declarations.has(namespace)) {
+        this.report(`Duplicate library name "${namespace}"`);
+        continue;
+      }
+      declarations.set(namespace, resolve(this.projectDir, src));
+    }
+
+    const workspaceLibraries =
+      this.options.libraries ??
+      loadWorkspaceConfig(this.projectDir).libraries ??
+      {};
+    for (const [namespace, path] of Object.entries(workspaceLibraries)) {
+      if (!declarations.has(namespace)) {
+        declarations.set(namespace, path);
+      }
+    }
+
+    // A library that fails to load is reported once, the others stay usable
+    this.libraries = [];
+    for (const [namespace, path] of declarations) {
+      try {
+        this.libraries.push(loadAssetLibrary(namespace, path));
+      } catch (error) {
+        this.failedLibraries.add(namespace);
+        this.report(error instanceof Error ? error.message : String(error));
+      }
+    }
+    return this.libraries;
+  }
+
+  /**
+   * Finds all <library> elements in the HTML
+   */
+  private findLibraryElements(): Element[] {
+    const results: Element[] = [];
+
+    const traverse = (node: ASTNode) => {
+      if (node.type === 'tag' && (node as Element).name === 'library') {
+        results.push(node as Element);
+      }
+
+      if ('children' in node && node.children) {
+        for (const child of node.children) {
+          traverse(child);
+        }
+      }
+    };
+
+    traverse(this.html.ast);
+    return results;
+  }
+
+  /**
+   * Extracts a sub-clip asset: a named time range of another asset
+   * Example: <asset name="beach-wave" from="beach" in="00:10" out="00:14" />
+   * The sub-clip shares the source's file (and ffmpeg input), no ffprobe is needed
+   */
+  private extractSubclipFromElement(
+    element: Element,
+    assets: Asset[],
+  ): Asset | null {
+    const attrs = getAttrs(element);
+
+    const name = attrs.get('data-name') || attrs.get('id') || attrs.get('name');
+    if (!name) {
+      this.report(
+        'Sub-clip asset element missing data-name, id or name attribute',
+      );
+      return null;
+    }
+
+    const from = attrs.get('from')!;
+    const source = assets.find((asset) => asset.name === from);
+    if (!source) {
+      this.report(`Sub-clip "${name}" references unknown asset "${from}"`);
+      return null;
+    }
+
+    if (source.type === 'image') {
+      this.report(`Sub-clip "${name}" cannot be made of image asset "${from}"`);
+      return null;
+    }
+
+    // A sub-clip of a sub-clip points to the original source
+    const offset = source.subclip ? source.subclip.start : 0;
+    const root = source.subclip ? source.subclip.from : source.name;
+
+    const inAttr = attrs.get('in');
+    const outAttr = attrs.get('out');
+    const start = inAttr ? this.parseTimecodeOrMilliseconds(inAttr) : 0;
+    const end = Math.min(
+      outAttr ? this.parseTimecodeOrMilliseconds(outAttr) : source.duration,
+      source.duration,
+    );
+
+    if (end <= start) {
+      this.report(
+        `Sub-clip "${name}" has an empty range (in=${start}ms, out=${end}ms)`,
+      );
+      return null;
+    }
+
+    // Extract author (optional, falls back to the source's author)
+    const author = attrs.get('data-author') || source.author;
+
+    // A/V sync correction (optional, falls back to the source's one)
+    const avOffset =
+      this.parseAvOffset(attrs.get('data-av-offset'), `asset "${name}"`) ??
+      source.avOffset;
+
+    return {
+      name,
+      path: source.path,
+      type: source.type,
+      duration: end - start,
+      width: source.width,
+      height: source.height,
+      rotation: source.rotation,
+      hasVideo: source.hasVideo,
+      hasAudio: source.hasAudio,
+      ...(source.variableFrameRate && { variableFrameRate: true }),
+      ...(source.hasAlpha && { hasAlpha: true }),
+      ...(source.alphaDecoder && { alphaDecoder: source.alphaDecoder }),
+      ...(avOffset && { avOffset }),
+      subclip: {
+        from: root,
+        start: offset + start,
+        end: offset + end,
+      },
+      ...(author && { author }),
+    };
+  }
+
   /**
    * Finds all asset elements in the HTML
    */
@@ -205,20 +759,19 @@ export class HTMLProjectParser {
    */
   private async extractAssetFromElement(
     element: Element,
+    attrs: Map<string, string> = getAttrs(element),
   ): Promise<Asset | null> {
-    const attrs = getAttrs(element);
-
     // Extract name (required)
     const name = attrs.get('data-name') || attrs.get('id');
     if (!name) {
-      console.warn('Asset element missing data-name or id attribute');
+      this.report('Asset element missing data-name or id attribute');
       return null;
     }
 
     // Extract path (required)
     const relativePath = attrs.get('data-path') || attrs.get('src');
     if (!relativePath) {
-      console.warn(`Asset "${name}" missing data-path or src attribute`);
+      this.report(`Asset "${name}" missing data-path or src attribute`);
       return null;
     }
 
@@ -254,9 +807,24 @@ export class HTMLProjectParser {
     // Check if asset has audio stream
     const hasAudio = await this.getHasAudio(absolutePath, type);
 
+    // Check if the video has a variable frame rate (phone footage)
+    const variableFrameRate = await this.getIsVariableFrameRate(
+      absolutePath,
+      type,
+    );
+
+    // Check for an alpha channel (overlays with transparency)
+    const { hasAlpha, alphaDecoder } = await this.getAlpha(absolutePath, type);
+
     // Extract author (optional)
     const author = attrs.get('data-author');
 
+    // A/V sync correction of the recording (optional)
+    const avOffset = this.parseAvOffset(
+      attrs.get('data-av-offset'),
+      `asset "${name}"`,
+    );
+
     // Extract AI configuration from child <ai> element (optional)
     const aiConfig = this.extractAssetAIConfig(element);
 
@@ -270,6 +838,10 @@ export class HTMLProjectParser {
       rotation,
       hasVideo,
       hasAudio,
+      ...(variableFrameRate && { variableFrameRate }),
+      ...(hasAlpha && { hasAlpha }),
+      ...(alphaDecoder && { alphaDecoder }),
+      ...(avOffset && { avOffset }),
       ...(author && { author }),
       ...(aiConfig && { ai: aiConfig }),
     };
@@ -293,7 +865,7 @@ export class HTMLProjectParser {
 
         const integrationName = attrs.get('data-integration-name');
         if (!integrationName) {
-          console.warn('Asset <ai> element missing data-integration-name attribute');
+          this.report('Asset <ai> element missing data-integration-name attribute');
           return null;
         }
 
@@ -330,7 +902,7 @@ export class HTMLProjectParser {
 
         prompt = prompt.trim();
         if (!prompt) {
-          console.warn('Asset <ai> element missing <prompt>');
+          this.report('Asset <ai> element missing <prompt>');
           return null;
         }
 
@@ -402,10 +974,11 @@ export class HTMLProjectParser {
   }
 
   /**
-   * Gets the rotation of an asset file using ffprobe
+   * Gets the rotation of an asset file using ffprobe: the display matrix
+   * (phone footage), or the legacy "rotate" tag of older files
    * @param path - Path to the asset file
    * @param type - Asset type (video, audio, or image)
-   * @returns Rotation in degrees (0, 90, 180, 270)
+   * @returns Clockwise rotation in degrees to display it upright (0, 90, 180, 270)
    */
   private async getAssetRotation(
     path: string,
@@ -422,22 +995,31 @@ export class HTMLProjectParser {
       '-select_streams',
       'v:0',
       '-show_entries',
-      'stream_side_data=rotation',
+      'stream_side_data=rotation:stream_tags=rotate',
       '-of',
-      'default=noprint_wrappers=1:nokey=1',
+      'default=noprint_wrappers=1',
       path,
     ]);
 
-    const rotation = parseInt(stdout.trim(), 10);
+    const values = new Map(
+      stdout
+        .trim()
+        .split('\n')
+        .map(
+          (line) => line.replace(/^TAG:/, '').split('=') as [string, string],
+        ),
+    );
 
-    if (isNaN(rotation)) {
-      // No rotation metadata found
-      return 0;
-    }
+    // The display matrix angle is counterclockwise, the rotate tag clockwise
+    const matrixRotation = parseFloat(values.get('rotation') ?? '');
+    const tagRotation = parseFloat(values.get('rotate') ?? '');
+    const rotation = !isNaN(matrixRotation)
+      ? -matrixRotation
+      : !isNaN(tagRotation)
+        ? tagRotation
+        : 0;
 
-    // Normalize to 0, 90, 180, 270
-    const normalized = Math.abs(rotation) % 360;
-    return normalized;
+    return normalizeRotation(rotation);
   }
 
   /**
@@ -539,6 +1121,92 @@ export class HTMLProjectParser {
     return stdout.trim() === 'audio';
   }
 
+  /**
+   * Checks if a video has a variable frame rate using ffprobe
+   * (average frame rate differs from the nominal one)
+   * @param path - Path to the asset file
+   * @param type - Asset type (video, audio, or image)
+   * @returns True if the video stream is variable frame rate
+   */
+  private async getIsVariableFrameRate(
+    path: string,
+    type: 'video' | 'image' | 'audio',
+  ): Promise<boolean> {
+    // Only videos have a frame rate
+    if (type !== 'video') {
+      return false;
+    }
+
+    const { stdout } = await execFileAsync('ffprobe', [
+      '-v',
+      'error',
+      '-select_streams',
+      'v:0',
+      '-show_entries',
+      'stream=r_frame_rate,avg_frame_rate',
+      '-of',
+      'default=noprint_wrappers=1',
+      path,
+    ]);
+
+    const rates = new Map(
+      stdout
+        .trim()
+        .split('\n')
+        .map((line) => line.split('=') as [string, string]),
+    );
+
+    return isVariableFrameRate(
+      rates.get('r_frame_rate') ?? '',
+      rates.get('avg_frame_rate') ?? '',
+    );
+  }
+
+  /**
+   * Checks if a video or image has an alpha channel using ffprobe: by its
+   * pixel format, or the alpha_mode tag of VP8/VP9 WebM files (their pixel
+   * format is reported without alpha, which only a libvpx decoder keeps)
+   * @param path - Path to the asset file
+   * @param type - Asset type (video, audio, or image)
+   */
+  private async getAlpha(
+    path: string,
+    type: 'video' | 'image' | 'audio',
+  ): Promise<{ hasAlpha: boolean; alphaDecoder?: string }> {
+    // Audio files don't have pixels
+    if (type === 'audio') {
+      return { hasAlpha: false };
+    }
+
+    const { stdout } = await execFileAsync('ffprobe', [
+      '-v',
+      'error',
+      '-select_streams',
+      'v:0',
+      '-show_entries',
+      'stream=codec_name,pix_fmt:stream_tags=alpha_mode',
+      '-of',
+      'default=noprint_wrappers=1',
+      path,
+    ]);
+
+    const values = new Map(
+      stdout
+        .trim()
+        .split('\n')
+        .map(
+          (line) => line.replace(/^TAG:/, '').split('=') as [string, string],
+        ),
+    );
+
+    const codec = values.get('codec_name') ?? '';
+    if (values.get('alpha_mode') === '1' && ALPHA_DECODERS[codec]) {
+      return { hasAlpha: true, alphaDecoder: ALPHA_DECODERS[codec] };
+    }
+
+    return { hasAlpha: ALPHA_PIXEL_FORMAT.test(values.get('pix_fmt') ?? '') };
+  }
+
   /**
    * Processes all output configurations from the parsed HTML
    * Returns a map of output name => Output definition
@@ -567,10 +1235,32 @@ export class HTMLProjectParser {
       // Extract name
       const name = attrs.get('name') || 'output';
 
+      // Extract audio-only format (mp3, aac, flac)
+      const format = this.parseOutputFormat(name, attrs.get('format'));
+
+      // Transparent output (alpha or alpha="true")
+      const alphaAttr = attrs.get('alpha');
+      const wantsAlpha =
+        alphaAttr !== undefined && alphaAttr.trim().toLowerCase() !== 'false';
+
       // Extract and resolve path
-      const relativePath = attrs.get('path') || `./output/${name}.mp4`;
+      const relativePath =
+        attrs.get('path') ||
+        `./output/${name}.${format ? AUDIO_FORMAT_EXTENSIONS[format] : wantsAlpha ? 'mov' : 'mp4'}`;
       const path = resolve(this.projectDir, relativePath);
 
+      const extension = extname(path).slice(1).toLowerCase();
+      let alpha = false;
+      if (wantsAlpha && format) {
+        this.report(`Output "${name}" is audio-only, alpha is ignored`);
+      } else if (wantsAlpha && !ALPHA_OUTPUT_EXTENSIONS.includes(extension)) {
+        this.report(
+          `Output "${name}" can't keep alpha in a .${extension} file, use one of: ${ALPHA_OUTPUT_EXTENSIONS.map((ext) => `.${ext}`).join(', ')}`,
+        );
+      } else {
+        alpha = wantsAlpha;
+      }
+
       // Extract and parse resolution (format: "1920x1080")
       const resolutionStr = attrs.get('resolution') || '1920x1080';
       const [widthStr, heightStr] = resolutionStr.split('x');
@@ -583,11 +1273,37 @@ export class HTMLProjectParser {
       const fpsStr = attrs.get('fps');
       const fps = fpsStr ? parseInt(fpsStr, 10) : 30;
 
+      // Extract bitrate (e.g. "8M", "2500k")
+      const bitrateStr = attrs.get('bitrate');
+      const bitrate =
+        bitrateStr && /^\d+(?:\.\d+)?[kKmM]?$/.test(bitrateStr)
+          ? bitrateStr
+          : undefined;
+      if (bitrateStr !== undefined && !bitrate) {
+        this.report(
+          `Invalid bitrate "${bitrateStr}" of output "${name}", expected e.g. "8M" or "2500k"`,
+        );
+      }
+
+      // Extract base size, the resolution containers are designed for,
+      // from the output or the <outputs> element of all outputs
+      const parent = element.parent as Element | null;
+      const baseSizeStr =
+        attrs.get('base-size') ??
+        (parent?.name === 'outputs'
+          ? getAttrs(parent).get('base-size')
+          : undefined);
+      const baseSize = this.parseBaseSize(name, baseSizeStr);
+
       const output: Output = {
         name,
         path,
         resolution,
         fps,
+        ...(format && { format }),
+        ...(bitrate && { bitrate }),
+        ...(alpha && { alpha }),
+        ...(baseSize && { baseSize }),
       };
 
       outputs.set(name, output);
@@ -596,6 +1312,200 @@ export class HTMLProjectParser {
     return outputs;
   }
 
+  /**
+   * Parses the base-size attribute of an output (format: "1920x1080")
+   */
+  private parseBaseSize(
+    name: string,
+    baseSize: string | undefined,
+  ): Output['baseSize'] {
+    if (baseSize === undefined) {
+      return undefined;
+    }
+
+    const match = baseSize.trim().match(/^(\d+)x(\d+)$/);
+    const width = match ? parseInt(match[1], 10) : 0;
+    const height = match ? parseInt(match[2], 10) : 0;
+    if (width === 0 || height === 0) {
+      this.report(
+        `Invalid base size "${baseSize}" of output "${name}", expected e.g. "1920x1080"`,
+      );
+      return undefined;
+    }
+
+    return { width, height };
+  }
+
+  /**
+   * Parses the format attribute of an output
+   * Only audio-only formats are accepted, video outputs have no format
+   */
+  private parseOutputFormat(
+    name: string,
+    format: string | undefined,
+  ): AudioFormat | undefined {
+    if (!format) {
+      return undefined;
+    }
+
+    const normalized = format.trim().toLowerCase();
+    if (normalized in AUDIO_FORMAT_EXTENSIONS) {
+      return normalized as AudioFormat;
+    }
+
+    this.report(
+      `Output "${name}" has unsupported format "${format}", expected one of: ${Object.keys(AUDIO_FORMAT_EXTENSIONS).join(', ')}`,
+    );
+    return undefined;
+  }
+
+  /**
+   * Processes <assert> elements, checked by "staticstripes test"
+   * Example: <assert at="00:05" sequence="main" contains-text="Welcome" />
+   */
+  private processAssertions(outputs: Map<string, Output>): Assertion[] {
+    const assertions: Assertion[] = [];
+
+    this.findAssertElements().forEach((element, index) => {
+      const attrs = getAttrs(element);
+      const name = attrs.get('id') || attrs.get('name') || `assert #${index + 1}`;
+      const assertion: Assertion = { name };
+
+      const output = attrs.get('output');
+      if (output !== undefined) {
+        if (!outputs.has(output)) {
+          this.report(`Assertion "${name}" references unknown output "${output}"`);
+          return;
+        }
+        assertion.output = output;
+      }
+
+      const at = attrs.get('at');
+      if (at !== undefined) {
+        assertion.at = this.parseTimecodeOrMilliseconds(at);
+      }
+
+      const sequence = attrs.get('sequence');
+      if (sequence !== undefined) {
+        assertion.sequence = sequence;
+      }
+
+      const containsText = attrs.get('contains-text');
+      if (containsText !== undefined) {
+        assertion.containsText = containsText;
+      }
+
+      // Pixel color: pixel="x,y" color="#rrggbb" [color-tolerance="16"]
+      const pixel = attrs.get('pixel');
+      if (pixel !== undefined) {
+        const match = pixel.trim().match(/^(\d+)\s*,\s*(\d+)$/);
+        const color = this.parseHexColor(attrs.get('color') ?? '');
+        if (!match || !color) {
+          this.report(
+            `Assertion "${name}" needs pixel="x,y" and color="#rrggbb"`,
+          );
+          return;
+        }
+
+        const tolerance = parseInt(attrs.get('color-tolerance') ?? '16', 10);
+        assertion.pixel = {
+          x: parseInt(match[1], 10),
+          y: parseInt(match[2], 10),
+          color,
+          tolerance: isNaN(tolerance) ? 16 : tolerance,
+        };
+      }
+
+      // Duration of the timeline (or of a sequence)
+      const duration = attrs.get('duration');
+      if (duration !== undefined) {
+        const tolerance = attrs.get('duration-tolerance');
+        assertion.duration = {
+          value: this.parseTimecodeOrMilliseconds(duration),
+          tolerance: tolerance ? this.parseMilliseconds(tolerance) : 100,
+        };
+      }
+
+      if (
+        assertion.containsText === undefined &&
+        !assertion.pixel &&
+        !assertion.duration
+      ) {
+        this.report(
+          `Assertion "${name}" checks nothing, use contains-text, pixel/color or duration`,
+        );
+        return;
+      }
+
+      if (
+        (assertion.containsText !== undefined || assertion.pixel) &&
+        assertion.at === undefined
+      ) {
+        this.report(`Assertion "${name}" needs the "at" attribute`);
+        return;
+      }
+
+      assertions.push(assertion);
+    });
+
+    return assertions;
+  }
+
+  /**
+   * Parses "#rgb" or "#rrggbb"
+   */
+  private parseHexColor(
+    value: string,
+  ): { r: number; g: number; b: number } | undefined {
+    let hex = value.trim().replace(/^#/, '');
+    if (/^[0-9a-f]{3}$/i.test(hex)) {
+      hex = hex
+        .split('')
+        .map((char) => char + char)
+        .join('');
+    }
+    if (!/^[0-9a-f]{6}$/i.test(hex)) {
+      return undefined;
+    }
+
+    return {
+      r: parseInt(hex.slice(0, 2), 16),
+      g: parseInt(hex.slice(2, 4), 16),
+      b: parseInt(hex.slice(4, 6), 16),
+    };
+  }
+
+  /**
+   * Finds all assert elements in the HTML
+   */
+  private findAssertElements(): Element[] {
+    const results: Element[] = [];
+
+    const traverse = (node: ASTNode) => {
+      if (node.type === 'tag') {
+        const element = node as Element;
+
+        if (element.name === 'assert') {
+          results.push(element);
+        }
+
+        // container content is free-form HTML
+        if (element.name === 'container') {
+          return;
+        }
+      }
+
+      if ('children' in node && node.children) {
+        for (const child of node.children) {
+          traverse(child);
+        }
+      }
+    };
+
+    traverse(this.html.ast);
+    return results;
+  }
+
   /**
    * Finds all output elements in the HTML
    */
@@ -655,38 +1565,131 @@ export class HTMLProjectParser {
                 }
               }
 
-              // Trim whitespace
-              args = args.trim();
+              // Trim whitespace
+              args = args.trim();
+
+              const option: FFmpegOption = {
+                name,
+                args,
+              };
+
+              options.set(name, option);
+            }
+          }
+        }
+      }
+    }
+
+    return options;
+  }
+
+  /**
+   * Finds all ffmpeg elements in the HTML
+   */
+  private findFfmpegElements(): Element[] {
+    const results: Element[] = [];
+
+    const traverse = (node: ASTNode) => {
+      if (node.type === 'tag') {
+        const element = node as Element;
+
+        // Check if element is an <ffmpeg> tag
+        if (element.name === 'ffmpeg') {
+          results.push(element);
+        }
+      }
+
+      if ('children' in node && node.children) {
+        for (const child of node.children) {
+          traverse(child);
+        }
+      }
+    };
+
+    traverse(this.html.ast);
+    return results;
+  }
+
+  /**
+   * Processes the <font> elements of the <fonts> section:
+   * <font family="Inter" src="./fonts/Inter-Bold.ttf" weight="700" /> or
+   * <font family="Roboto" google="Roboto" style="italic" />
+   */
+  private processFonts(): Font[] {
+    const fonts: Font[] = [];
+    const projectDir = dirname(this.projectPath);
+
+    for (const fontsElement of this.findFontsElements()) {
+      for (const child of fontsElement.children) {
+        if (child.type !== 'tag' || (child as Element).name !== 'font') {
+          continue;
+        }
+        const attrs = getAttrs(child as Element);
+
+        const family = attrs.get('family');
+        if (!family) {
+          this.report('<font> is missing the family attribute');
+          continue;
+        }
+
+        const src = attrs.get('src');
+        const google = attrs.get('google');
+        if (!src === !google) {
+          this.report(
+            `<font family="${family}"> needs either a src (local file) or a google (Google Fonts family) attribute`,
+          );
+          continue;
+        }
+
+        const style = attrs.get('style') ?? 'normal';
+        if (style !== 'normal' && style !== 'italic') {
+          this.report(
+            `Invalid style "${style}" of <font family="${family}">, expected normal or italic`,
+          );
+          continue;
+        }
 
-              const option: FFmpegOption = {
-                name,
-                args,
-              };
+        const weight = attrs.get('weight') ?? '400';
+        if (!/^[1-9]00$/.test(weight)) {
+          this.report(
+            `Invalid weight "${weight}" of <font family="${family}">, expected 100-900`,
+          );
+          continue;
+        }
 
-              options.set(name, option);
-            }
+        const font: Font = { family, weight, style };
+        if (src) {
+          font.path = resolve(projectDir, src);
+          if (!isSupportedFontFile(font.path)) {
+            this.report(
+              `Unsupported font file ${src} of "${family}", use .ttf, .otf, .woff or .woff2`,
+            );
+            continue;
+          }
+          if (!existsSync(font.path)) {
+            this.report(`Font file of "${family}" not found: ${font.path}`);
+            continue;
           }
+        } else {
+          font.google = google;
         }
+
+        fonts.push(font);
       }
     }
 
-    return options;
+    return fonts;
   }
 
   /**
-   * Finds all ffmpeg elements in the HTML
+   * Finds all fonts elements in the HTML
    */
-  private findFfmpegElements(): Element[] {
+  private findFontsElements(): Element[] {
     const results: Element[] = [];
 
     const traverse = (node: ASTNode) => {
-      if (node.type === 'tag') {
-        const element = node as Element;
-
-        // Check if element is an <ffmpeg> tag
-        if (element.name === 'ffmpeg') {
-          results.push(element);
-        }
+      if (node.type === 'tag' && (node as Element).name === 'fonts') {
+        results.push(node as Element);
       }
 
       if ('children' in node && node.children) {
@@ -738,6 +1741,7 @@ export class HTMLProjectParser {
             }
 
             if (upload) {
+              this.interpolateUpload(upload);
               uploads.set(upload.name, upload);
             }
           }
@@ -762,7 +1766,7 @@ export class HTMLProjectParser {
     const outputName = attrs.get('data-output-name');
 
     if (!name || !outputName) {
-      console.warn('YouTube upload missing name or data-output-name attribute');
+      this.report('YouTube upload missing name or data-output-name attribute');
       return null;
     }
 
@@ -898,7 +1902,7 @@ export class HTMLProjectParser {
     const outputName = attrs.get('data-output-name');
 
     if (!name || !outputName) {
-      console.warn('S3 upload missing name or data-output-name attribute');
+      this.report('S3 upload missing name or data-output-name attribute');
       return null;
     }
 
@@ -981,7 +1985,7 @@ export class HTMLProjectParser {
 
     // Validate required fields
     if (!region || !bucket || paths.size === 0) {
-      console.warn(`S3 upload "${name}" missing required fields (region, bucket, or path)`);
+      this.report(`S3 upload "${name}" missing required fields (region, bucket, or path)`);
       return null;
     }
 
@@ -1023,7 +2027,7 @@ export class HTMLProjectParser {
     const outputName = attrs.get('data-output-name');
 
     if (!name || !outputName) {
-      console.warn('Instagram upload missing name or data-output-name attribute');
+      this.report('Instagram upload missing name or data-output-name attribute');
       return null;
     }
 
@@ -1185,7 +2189,7 @@ export class HTMLProjectParser {
 
     const name = attrs.get('name');
     if (!name) {
-      console.warn('AI provider missing name attribute');
+      this.report('AI provider missing name attribute');
       return null;
     }
 
@@ -1313,6 +2317,85 @@ export class HTMLProjectParser {
     return title.trim() || 'Untitled Project';
   }
 
+  /**
+   * Replaces the build info variables ({{ .RenderDate }}, {{ .GitCommit }},
+   * {{ .ProjectVersion }}) in a text, reports unknown ones
+   * @param where - Where the text comes from, for the report
+   */
+  private interpolateBuildInfo(text: string, where: string): string {
+    if (!hasBuildInfoVariables(text)) {
+      return text;
+    }
+
+    if (!this.buildInfo) {
+      this.buildInfo = getBuildInfo(
+        dirname(this.projectPath),
+        this.processVersion(),
+      );
+    }
+
+    const { text: result, unknown } = interpolateBuildInfo(
+      text,
+      this.buildInfo,
+    );
+    for (const name of unknown) {
+      this.report(
+        `Unknown variable {{ .${name} }} in ${where}, available: ${BUILD_INFO_VARIABLES.join(', ')}`,
+      );
+    }
+    return result;
+  }
+
+  private interpolateUpload(upload: Upload): void {
+    const where = `upload "${upload.name}"`;
+    if (upload.title) {
+      upload.title = this.interpolateBuildInfo(upload.title, where);
+    }
+    upload.description = this.interpolateBuildInfo(upload.description, where);
+    if (upload.instagram) {
+      upload.instagram.caption = this.interpolateBuildInfo(
+        upload.instagram.caption,
+        where,
+      );
+    }
+  }
+
+  /**
+   * Processes the project version from the first top-level <version> element
+   */
+  private processVersion(): string {
+    const versions: string[] = [];
+
+    const traverse = (node: ASTNode, insideProject: boolean = false) => {
+      if (node.type === 'tag') {
+        const element = node as Element;
+        if (element.name === 'version' && !insideProject) {
+          let version = '';
+          for (const textNode of element.children) {
+            if (textNode.type === 'text' && 'data' in textNode) {
+              version += textNode.data;
+            }
+          }
+          versions.push(version.trim());
+          return;
+        }
+        insideProject =
+          insideProject ||
+          element.name === 'project' ||
+          element.name === 'uploads' ||
+          element.name === 'outputs';
+      }
+      if ('children' in node && node.children) {
+        for (const child of node.children) {
+          traverse(child, insideProject);
+        }
+      }
+    };
+
+    traverse(this.html.ast);
+    return versions[0] ?? '';
+  }
+
   /**
    * Processes the date from the parsed HTML
    */
@@ -1446,6 +2529,7 @@ export class HTMLProjectParser {
     assets.forEach((ass) => assetMap.set(ass.name, ass));
 
     for (const sequenceElement of sequenceElements) {
+      const inheritedStyles = this.getInheritedStyles(sequenceElement);
       const fragmentElements = this.findFragmentChildren(sequenceElement);
       const rawFragments: Array<
         Fragment & {
@@ -1455,7 +2539,11 @@ export class HTMLProjectParser {
       > = [];
 
       for (const fragmentElement of fragmentElements) {
-        const fragment = this.processFragment(fragmentElement, assetMap);
+        const fragment = this.processFragment(
+          fragmentElement,
+          assetMap,
+          inheritedStyles,
+        );
         if (fragment) {
           rawFragments.push(fragment);
         }
@@ -1511,12 +2599,35 @@ export class HTMLProjectParser {
         };
       });
 
-      sequences.push({ fragments });
+      const sequenceId = getAttrs(sequenceElement).get('id');
+      const markers = this.findMarkers(sequenceElement);
+      sequences.push({
+        ...(sequenceId && { id: sequenceId }),
+        fragments,
+        ...(markers.length > 0 && { markers }),
+      });
     }
 
     return sequences;
   }
 
+  /**
+   * Collects the inheritable properties set on a <sequence> element
+   * (via classes, tag selector or inline style)
+   */
+  private getInheritedStyles(sequenceElement: Element): CSSProperties {
+    const sequenceStyles = this.html.css.get(sequenceElement) || {};
+    const inherited: CSSProperties = {};
+
+    for (const property of INHERITED_PROPERTIES) {
+      if (sequenceStyles[property] !== undefined) {
+        inherited[property] = sequenceStyles[property];
+      }
+    }
+
+    return inherited;
+  }
+
   /**
    * Finds all sequence elements that are direct children of <project>
    */
@@ -1570,7 +2681,7 @@ export class HTMLProjectParser {
   }
 
   /**
-   * Finds all fragment descendants of a sequence element (not just direct children)
+   * Finds all fragment and gap descendants of a sequence element (not just direct children)
    * Parse5 treats self-closing custom tags as opening tags, nesting subsequent elements
    */
   private findFragmentChildren(sequenceElement: Element): Element[] {
@@ -1579,7 +2690,7 @@ export class HTMLProjectParser {
     const traverse = (node: ASTNode) => {
       if (node.type === 'tag') {
         const element = node as Element;
-        if (element.name === 'fragment') {
+        if (element.name === 'fragment' || element.name === 'gap') {
           fragments.push(element);
         }
       }
@@ -1601,6 +2712,46 @@ export class HTMLProjectParser {
     return fragments;
   }
 
+  /**
+   * Finds the <marker> elements of a sequence, each one points at the
+   * fragment (or gap) that follows it
+   */
+  private findMarkers(sequenceElement: Element): Marker[] {
+    const markers: Marker[] = [];
+    let fragmentIndex = 0;
+
+    const traverse = (node: ASTNode) => {
+      if (node.type === 'tag') {
+        const element = node as Element;
+        if (element.name === 'fragment' || element.name === 'gap') {
+          fragmentIndex++;
+        } else if (element.name === 'marker') {
+          const name = getAttrs(element).get('name')?.trim();
+          if (!name) {
+            this.report('<marker> is missing the name attribute');
+          } else if (this.markerNames.has(name)) {
+            this.report(`Duplicate marker name "${name}"`);
+          } else {
+            this.markerNames.add(name);
+            markers.push({ name, fragmentIndex });
+          }
+        }
+      }
+
+      if ('children' in node && node.children) {
+        for (const child of node.children) {
+          traverse(child);
+        }
+      }
+    };
+
+    for (const child of sequenceElement.children) {
+      traverse(child);
+    }
+
+    return markers;
+  }
+
   /**
    * Processes a single fragment element according to Parser.md specification
    * Returns fragment with temporary overlayRight and overlayZIndexRight for normalization
@@ -1608,6 +2759,7 @@ export class HTMLProjectParser {
   private processFragment(
     element: Element,
     assets: Map<string, Asset>,
+    inheritedStyles: CSSProperties = {},
   ):
     | (Fragment & {
         overlayRight: number | CompiledExpression;
@@ -1615,22 +2767,42 @@ export class HTMLProjectParser {
       })
     | null {
     const attrs = getAttrs(element);
-    const styles = this.html.css.get(element) || {};
+    // Fragment's own properties override the ones inherited from the sequence
+    const styles = {
+      ...inheritedStyles,
+      ...(this.html.css.get(element) || {}),
+    };
 
     // 1. Extract fragment ID from id attribute or generate one
     const id =
       attrs.get('id') ||
       `fragment_${Math.random().toString(36).substring(2, 11)}`;
 
+    // 1b. <gap> elements are pauses without an asset
+    const gap = element.name === 'gap' ? this.parseGap(attrs, id) : undefined;
+
     // 2. Extract assetName from attribute or CSS -asset property
-    const assetName = attrs.get('data-asset') || styles['-asset'] || '';
+    const assetName = gap
+      ? ''
+      : attrs.get('data-asset') || styles['-asset'] || '';
 
     // 3. Check enabled flag from display property
     const enabled = this.parseEnabled(styles['display']);
 
     // 4. Extract container or app if present (first one only, mutually exclusive)
-    const container = this.extractFragmentContainer(element);
-    const app = container ? undefined : this.extractFragmentApp(element);
+    const ownContainer = this.extractFragmentContainer(element);
+    const app = ownContainer ? undefined : this.extractFragmentApp(element);
+
+    // 4a. Add the dim layer (-scrim or <scrim>) under the container's text
+    const container = gap
+      ? ownContainer
+      : this.applyFragmentScrim(
+          element,
+          styles['-scrim'],
+          id,
+          ownContainer,
+          !!assetName || !!app,
+        );
 
     // 4b. Parse data-timing attribute (short syntax) - takes precedence over CSS
     const dataTiming = this.parseDataTiming(attrs.get('data-timing'));
@@ -1647,11 +2819,16 @@ export class HTMLProjectParser {
         ? dataTiming.trimEnd
         : this.parseTrimEnd(styles['-trim-end']);
 
+    // 5c. Parse -freeze / -freeze-at (needed for the auto duration)
+    const freeze = gap
+      ? undefined
+      : this.parseFreezeProperty(styles['-freeze'], styles['-freeze-at']);
+
     // 6. Parse duration from duration attribute, data-timing, or -duration property
     const durationAttr = attrs.get('duration');
     const duration =
       durationAttr !== undefined && durationAttr !== null
-        ? this.parseMilliseconds(durationAttr + 's') // Direct duration attribute as seconds (e.g., "3" → "3s" → 3000ms)
+        ? this.parseDurationAttribute(durationAttr)
         : dataTiming.duration !== undefined
         ? dataTiming.duration
         : this.parseDurationProperty(
@@ -1660,6 +2837,7 @@ export class HTMLProjectParser {
             assets,
             trimLeft,
             trimRight,
+            freeze,
           );
 
     // 7. Parse overlayLeft from data-timing or -offset-start property
@@ -1696,8 +2874,22 @@ export class HTMLProjectParser {
     // 14. Parse -chromakey
     const chromakeyData = this.parseChromakeyProperty(styles['-chromakey']);
 
-    // 14b. Parse -object-fit-ken-burns
-    const kenBurnsData = this.parseKenBurnsProperty(styles['-object-fit-ken-burns']);
+    // 14b. Parse -ken-burns, the shorthand wins over -object-fit and
+    // -object-fit-ken-burns
+    const kenBurnsShorthand = this.parseKenBurnsShorthand(
+      styles['-ken-burns'],
+      id,
+      assets.get(assetName),
+      this.html.css.get(element)?.['-ken-burns'] === undefined,
+    );
+    if (kenBurnsShorthand) {
+      objectFitData.objectFit = 'ken-burns';
+    }
+
+    // 14c. Parse -object-fit-ken-burns
+    const kenBurnsData =
+      kenBurnsShorthand ??
+      this.parseKenBurnsProperty(styles['-object-fit-ken-burns']);
 
     // 15. Parse filter (for visual filters)
     const visualFilter = this.parseVisualFilterProperty(styles['filter']);
@@ -1705,6 +2897,15 @@ export class HTMLProjectParser {
     // 16. Parse sound property (on/off)
     const sound = this.parseSoundProperty(styles['-sound']);
 
+    // 16b. Parse -orient (overrides the asset's rotation metadata)
+    const orientation = this.parseOrientProperty(styles['-orient']);
+
+    // 16c. Parse -av-offset (overrides the asset's data-av-offset)
+    const avOffset = this.parseAvOffset(
+      styles['-av-offset'],
+      `fragment "${id}"`,
+    );
+
     // 17. Extract timecode label from data-timecode attribute
     const timecodeLabel = attrs.get('data-timecode') || undefined;
 
@@ -1746,14 +2947,104 @@ export class HTMLProjectParser {
       objectFitKenBurnsPanStartY: kenBurnsData.objectFitKenBurnsPanStartY,
       objectFitKenBurnsPanEndX: kenBurnsData.objectFitKenBurnsPanEndX,
       objectFitKenBurnsPanEndY: kenBurnsData.objectFitKenBurnsPanEndY,
+      ...(kenBurnsShorthand?.objectFitKenBurnsZoomBase && {
+        objectFitKenBurnsZoomBase: kenBurnsShorthand.objectFitKenBurnsZoomBase,
+      }), // Add the start zoom of -ken-burns if it's not 1.0
       sound,
       ...(visualFilter && { visualFilter }), // Add visualFilter if present
       ...(container && { container }), // Add container if present
       ...(app && { app }), // Add app if present
       ...(timecodeLabel && { timecodeLabel }), // Add timecode label if present
+      ...(freeze && { freeze }), // Add freeze-frame if present
+      ...(orientation !== undefined && { orientation }), // Add orientation override if present
+      ...(avOffset !== undefined && { avOffset }), // Add A/V offset override if present
+      ...(gap && { gap }), // Add gap if the element is a <gap>
     };
   }
 
+  /**
+   * Parses an A/V sync offset (-av-offset or data-av-offset)
+   * Format: <time>, e.g. "120ms", "-0.2s", "80" (ms). Positive values delay
+   * the audio against the picture, negative ones play it earlier.
+   * @param where - Owner of the value, for the report
+   */
+  private parseAvOffset(
+    value: string | undefined,
+    where: string,
+  ): number | undefined {
+    const trimmed = value?.trim();
+    if (!trimmed) {
+      return undefined;
+    }
+
+    if (!/^[-+]?\d+(?:\.\d+)?(?:ms|s)?$/.test(trimmed)) {
+      this.report(
+        `Invalid A/V offset "${trimmed}" of ${where}, expected a time like 120ms or -0.2s`,
+      );
+      return undefined;
+    }
+    return this.parseMilliseconds(trimmed.replace(/^\+/, ''));
+  }
+
+  /**
+   * Parses -orient property
+   * Format: auto | <angle>, the clockwise rotation that shows the source
+   * upright, e.g. "90", "-90deg", "180". "auto" (default) uses the
+   * rotation metadata of the asset.
+   */
+  private parseOrientProperty(orient: string | undefined): number | undefined {
+    const value = orient?.trim().toLowerCase();
+    if (!value || value === 'auto') {
+      return undefined;
+    }
+
+    const match = value.match(/^(-?\d+)(?:deg)?$/);
+    const angle = match ? parseInt(match[1], 10) : NaN;
+    if (isNaN(angle) || angle % 90 !== 0) {
+      this.report(
+        `Invalid -orient value "${orient}", expected "auto" or a multiple of 90 (e.g. "90", "-90deg", "180")`,
+      );
+      return undefined;
+    }
+
+    return normalizeRotation(angle);
+  }
+
+  /**
+   * Parses a <gap> element's attributes
+   * The color defaults to transparent, which renders as black in the first
+   * sequence and lets the layers below show through in overlay sequences.
+   * The color goes into FFmpeg's color source as is, so only hex and color
+   * names are accepted (rgb() would break the filter graph).
+   */
+  private parseGap(attrs: Map<string, string>, id: string): Gap {
+    const color = attrs.get('color')?.trim();
+
+    if (!color || color === 'transparent') {
+      return { color: '#00000000' };
+    }
+
+    // FFmpeg has no short hex, #f00 → #ff0000
+    const shortHex = /^#([0-9a-f]{3,4})$/i.exec(color);
+    if (shortHex) {
+      return {
+        color: `#${Array.from(shortHex[1], (digit) => digit + digit).join('')}`,
+      };
+    }
+
+    if (
+      !/^#(?:[0-9a-f]{6}|[0-9a-f]{8})$/i.test(color) &&
+      !isColorName(color)
+    ) {
+      this.report(
+        `Invalid color "${color}" of gap "${id}", expected a hex color (#rrggbb, #rrggbbaa) or a CSS color name`,
+      );
+      return { color: '#00000000' };
+    }
+
+    return { color };
+  }
+
   /**
    * Parses filter property (for visual filters)
    * Format: "<filter-name>"
@@ -1792,6 +3083,47 @@ export class HTMLProjectParser {
     return 'on'; // Default for any other value
   }
 
+  /**
+   * Parses -freeze and -freeze-at properties
+   * Formats:
+   *   -freeze: <first|last> <duration>   e.g. "last 2s" holds the last frame for 2s
+   *   -freeze-at: <timecode> [<duration>] e.g. "00:03.5" shows the frame at 3.5s
+   * -freeze-at takes precedence when both are set
+   */
+  private parseFreezeProperty(
+    freeze: string | undefined,
+    freezeAt: string | undefined,
+  ): Freeze | undefined {
+    if (freezeAt) {
+      const parts = this.splitCssValue(freezeAt.trim());
+      const time =
+        parts.length > 0 ? this.parseTimecodeOrMilliseconds(parts[0]) : 0;
+      const duration = parts.length > 1 ? this.parseMilliseconds(parts[1]) : 0;
+
+      return { mode: 'at', duration: Math.max(0, duration), time };
+    }
+
+    if (!freeze) {
+      return undefined;
+    }
+
+    const parts = this.splitCssValue(freeze.trim());
+    const mode = parts[0]?.toLowerCase();
+    if (mode !== 'first' && mode !== 'last') {
+      this.report(
+        `Invalid -freeze value "${freeze}", expected "first <duration>" or "last <duration>"`,
+      );
+      return undefined;
+    }
+
+    const duration = parts.length > 1 ? this.parseMilliseconds(parts[1]) : 0;
+    if (duration <= 0) {
+      return undefined;
+    }
+
+    return { mode, duration, time: 0 };
+  }
+
   /**
    * Extracts the first <container> child from a fragment element
    */
@@ -1811,7 +3143,10 @@ export class HTMLProjectParser {
           `container_${Math.random().toString(36).substring(2, 11)}`;
 
         // Get innerHTML (serialize all children)
-        const htmlContent = this.serializeElement(containerElement);
+        const htmlContent = this.interpolateBuildInfo(
+          this.serializeElement(containerElement),
+          `container "${id}"`,
+        );
 
         return {
           id,
@@ -1823,6 +3158,106 @@ export class HTMLProjectParser {
     return undefined;
   }
 
+  /**
+   * Adds the scrim of a fragment to its container. A fragment with just a
+   * scrim (no asset, container or app) becomes a container of its own, to
+   * dim everything of the sequences below it.
+   */
+  private applyFragmentScrim(
+    element: Element,
+    scrimProperty: string | undefined,
+    fragmentId: string,
+    container: Container | undefined,
+    hasOtherContent: boolean,
+  ): Container | undefined {
+    const scrim = this.parseFragmentScrim(element, scrimProperty, fragmentId);
+    if (!scrim) {
+      return container;
+    }
+
+    const scrimHtml = makeScrimHtml(scrim);
+    if (container) {
+      return { ...container, htmlContent: scrimHtml + container.htmlContent };
+    }
+
+    if (hasOtherContent) {
+      this.report(
+        `Scrim of fragment "${fragmentId}" needs a <container>, or a fragment of its own in a sequence between the video and the text`,
+      );
+      return undefined;
+    }
+
+    return { id: `${fragmentId}_scrim`, htmlContent: scrimHtml };
+  }
+
+  /**
+   * Parses the scrim of a fragment, the -scrim property wins over a <scrim>
+   * child element
+   * Format: "<solid|gradient> [color] [opacity] [direction] [extent%]"
+   * Examples:
+   *   - "gradient" - black, 60% at the bottom edge, fading out at 50%
+   *   - "gradient #102040 0.8 top 35%"
+   *   - "solid rgba(0,0,0) 0.4"
+   * <scrim type="gradient" color="#000" opacity="0.6" direction="bottom" extent="50%" />
+   */
+  private parseFragmentScrim(
+    element: Element,
+    scrimProperty: string | undefined,
+    fragmentId: string,
+  ): Scrim | undefined {
+    let values: string[];
+    if (scrimProperty?.trim()) {
+      // spaces inside of a color function, e.g. rgb(0 0 0), don't split
+      values = scrimProperty.trim().split(/\s+(?![^(]*\))/);
+    } else {
+      const scrimElement = element.children.find(
+        (child): child is Element =>
+          child.type === 'tag' && (child as Element).name === 'scrim',
+      );
+      if (!scrimElement) {
+        return undefined;
+      }
+      const attrs = getAttrs(scrimElement);
+      values = ['type', 'color', 'opacity', 'direction', 'extent']
+        .map((name) => attrs.get(name)?.trim())
+        .filter((value): value is string => !!value);
+    }
+
+    const scrim: Scrim = { ...DEFAULT_SCRIM };
+    for (const value of values) {
+      if (value === 'solid' || value === 'gradient') {
+        scrim.type = value;
+      } else if (
+        value === 'top' ||
+        value === 'bottom' ||
+        value === 'left' ||
+        value === 'right'
+      ) {
+        scrim.direction = value;
+      } else if (/^\d+(?:\.\d+)?%$/.test(value)) {
+        scrim.extent = Math.min(100, parseFloat(value));
+      } else if (/^\d*\.?\d+$/.test(value)) {
+        const opacity = parseFloat(value);
+        if (opacity > 1) {
+          this.report(
+            `Invalid scrim opacity "${value}" of fragment "${fragmentId}", expected 0-1`,
+          );
+          return undefined;
+        }
+        scrim.opacity = opacity;
+      } else if (isScrimColor(value)) {
+        scrim.color = value;
+      } else {
+        this.report(
+          `Invalid scrim value "${value}" of fragment "${fragmentId}", expected solid/gradient, a color (hex, rgb(), hsl() or a CSS name), an opacity 0-1, top/bottom/left/right or an extent like 50%`,
+        );
+        return undefined;
+      }
+    }
+
+    return scrim;
+  }
+
   /**
    * Extracts the first <app> child from a fragment element.
    * The src attribute points to the app's dst directory (relative to project).
@@ -1850,11 +3285,14 @@ export class HTMLProjectParser {
             const parsed = JSON.parse(dataParameters);
             // Convert all values to strings for query parameters
             for (const [key, value] of Object.entries(parsed)) {
-              parameters[key] = String(value);
+              parameters[key] = this.interpolateBuildInfo(
+                String(value),
+                `app "${id}"`,
+              );
             }
           } catch {
-            console.warn(
-              `Warning: invalid JSON in data-parameters for app "${id}": ${dataParameters}`,
+            this.report(
+              `Invalid JSON in data-parameters for app "${id}": ${dataParameters}`,
             );
           }
         }
@@ -1993,14 +3431,27 @@ export class HTMLProjectParser {
     assets: Map<string, Asset>,
     trimLeft: number,
     trimRight: number,
+    freeze?: Freeze,
   ): number | CompiledExpression {
     if (!duration || duration.trim() === 'auto') {
+      // -freeze-at with a hold time: the fragment lasts as long as the hold
+      if (freeze && freeze.mode === 'at' && freeze.duration > 0) {
+        return freeze.duration;
+      }
+
       // Auto: use asset duration minus trim-start and trim-end
       const asset = assets.get(assetName);
       if (!asset) {
         return 0;
       }
-      return Math.max(0, asset.duration - trimLeft - trimRight);
+      const playDuration = Math.max(0, asset.duration - trimLeft - trimRight);
+
+      // -freeze: first/last extends the clip by the hold time
+      if (freeze && freeze.mode !== 'at' && asset.type === 'video') {
+        return playDuration + freeze.duration;
+      }
+
+      return playDuration;
     }
 
     const trimmed = duration.trim();
@@ -2066,6 +3517,29 @@ export class HTMLProjectParser {
     return 0;
   }
 
+  /**
+   * Parses a timecode ("00:03.5", "01:02:03") or a time value ("3.5s", "3500ms")
+   * into milliseconds
+   */
+  private parseTimecodeOrMilliseconds(value: string): number {
+    return parseTimecode(value) ?? this.parseMilliseconds(value);
+  }
+
+  /**
+   * Parses the duration attribute of a fragment or gap
+   * Unitless values are seconds (e.g. "3" → 3000ms), values with units
+   * are parsed as usual (e.g. "2s", "500ms")
+   */
+  private parseDurationAttribute(value: string): number {
+    const trimmed = value.trim();
+
+    if (/^\d+(?:\.\d+)?$/.test(trimmed)) {
+      return this.parseMilliseconds(trimmed + 's');
+    }
+
+    return this.parseMilliseconds(trimmed);
+  }
+
   /**
    * Parses -offset-start into overlayLeft
    * Can be a time value or a calc() expression
@@ -2391,6 +3865,150 @@ export class HTMLProjectParser {
     };
   }
 
+  /**
+   * Parses -ken-burns, a shorthand of "-object-fit: ken-burns" and
+   * -object-fit-ken-burns for still images
+   * Format:
+   *   Zoom effects: "<zoom-in|zoom-out> [<from> <to>] [<anchor>] [easing]"
+   *   Pan effects: "<pan-left|pan-right|pan-top|pan-bottom> [<scale>] [easing]"
+   * Examples:
+   *   - "zoom-in 1.0 1.15 center" - zoom from 100% to 115% into the center
+   *   - "zoom-out 1.3 1.0 top-left ease-out"
+   *   - "pan-right 1.2" - at 120%, from the left edge to the right one
+   * From/to/scale are scale factors (default 1.0 1.15 for zoom-in, 1.15 1.0
+   * for zoom-out, 1.15 for pans). Anchor: center (default), top, bottom,
+   * left, right, top-left, top-right, bottom-left, bottom-right.
+   * The effect lasts the whole fragment.
+   */
+  private parseKenBurnsShorthand(
+    value: string | undefined,
+    fragmentId: string,
+    asset: Asset | undefined,
+    inherited: boolean = false,
+  ) {
+    if (!value || !value.trim()) {
+      return undefined;
+    }
+
+    // e.g. -ken-burns of a slideshow sequence with a video clip in it
+    if (inherited && asset && asset.type !== 'image') {
+      return undefined;
+    }
+
+    const invalid = (reason: string) => {
+      this.report(
+        `Invalid -ken-burns "${value}" of fragment "${fragmentId}": ${reason}`,
+      );
+      return undefined;
+    };
+
+    if (asset && asset.type !== 'image') {
+      return invalid(
+        `it applies to images, "${asset.name}" is a ${asset.type}`,
+      );
+    }
+
+    const parts = this.splitCssValue(value.trim());
+    const effect = parts.shift()!;
+    const defaults = this.parseKenBurnsProperty(undefined);
+
+    // Trailing easing, then the scale factors, then the anchor
+    let easing = defaults.objectFitKenBurnsEasing;
+    const easings = ['linear', 'ease-in', 'ease-out', 'ease-in-out'] as const;
+    const lastPart = parts[parts.length - 1];
+    const easingIndex = easings.findIndex((name) => name === lastPart);
+    if (easingIndex >= 0) {
+      easing = easings[easingIndex];
+      parts.pop();
+    }
+
+    const scales: number[] = [];
+    while (parts.length > 0 && /^\d+(?:\.\d+)?$/.test(parts[0])) {
+      scales.push(parseFloat(parts.shift()!));
+    }
+    if (scales.some((scale) => scale < 1)) {
+      return invalid('scale factors must be 1.0 or more');
+    }
+    // Scale factors to the zoom percentages of -object-fit-ken-burns
+    const toZoom = (scale: number) => Math.round((scale - 1) * 10000) / 100;
+
+    if (effect === 'zoom-in' || effect === 'zoom-out') {
+      if (scales.length !== 0 && scales.length !== 2) {
+        return invalid('expected both the <from> and the <to> scale');
+      }
+      const [from, to] =
+        scales.length === 2
+          ? scales
+          : effect === 'zoom-in'
+            ? [1, KEN_BURNS_SCALE]
+            : [KEN_BURNS_SCALE, 1];
+      if (effect === 'zoom-in' ? from >= to : from <= to) {
+        return invalid(
+          `${effect} needs a ${effect === 'zoom-in' ? 'larger' : 'smaller'} <to> scale than <from>`,
+        );
+      }
+
+      const anchor = parts.shift() ?? 'center';
+      const focal = KEN_BURNS_ANCHORS[anchor];
+      if (!focal || parts.length > 0) {
+        return invalid(
+          `unknown anchor "${[anchor, ...parts].join(' ')}", expected one of ${Object.keys(KEN_BURNS_ANCHORS).join(', ')}`,
+        );
+      }
+
+      return {
+        ...defaults,
+        objectFitKenBurns: effect,
+        objectFitKenBurnsZoom: toZoom(Math.max(from, to)),
+        objectFitKenBurnsZoomBase: toZoom(Math.min(from, to)),
+        objectFitKenBurnsEasing: easing,
+        objectFitKenBurnsFocalX: focal[0],
+        objectFitKenBurnsFocalY: focal[1],
+      };
+    }
+
+    if (
+      effect === 'pan-left' ||
+      effect === 'pan-right' ||
+      effect === 'pan-top' ||
+      effect === 'pan-bottom'
+    ) {
+      if (scales.length > 1 || parts.length > 0) {
+        return invalid(`expected "${effect} [<scale>] [easing]"`);
+      }
+      const scale = scales[0] ?? KEN_BURNS_SCALE;
+      if (scale <= 1) {
+        return invalid('a pan needs a scale above 1.0 to have room to move');
+      }
+
+      // The view moves in the direction of the effect
+      const [start, end] =
+        effect === 'pan-left' || effect === 'pan-top' ? [100, 0] : [0, 100];
+      const horizontal = effect === 'pan-left' || effect === 'pan-right';
+
+      return {
+        ...defaults,
+        objectFitKenBurns: effect,
+        objectFitKenBurnsZoom: toZoom(scale),
+        objectFitKenBurnsZoomBase: 0,
+        objectFitKenBurnsEasing: easing,
+        ...(horizontal
+          ? {
+              objectFitKenBurnsPanStartX: start,
+              objectFitKenBurnsPanEndX: end,
+            }
+          : {
+              objectFitKenBurnsPanStartY: start,
+              objectFitKenBurnsPanEndY: end,
+            }),
+      };
+    }
+
+    return invalid(
+      'expected zoom-in, zoom-out, pan-left, pan-right, pan-top or pan-bottom',
+    );
+  }
+
   /**
    * Parses -object-fit-ken-burns property
    * Format:
@@ -0,0 +1,47 @@
+/**
+ * Records everything written to stdout and stderr while still printing it,
+ * e.g. to attach the console output of a failed render to a crash report
+ */
+export class LogCapture {
+  private chunks: string[] = [];
+  private originalStdoutWrite: typeof process.stdout.write | null = null;
+  private originalStderrWrite: typeof process.stderr.write | null = null;
+
+  public start(): void {
+    if (this.originalStdoutWrite) {
+      return;
+    }
+
+    this.originalStdoutWrite = process.stdout.write;
+    this.originalStderrWrite = process.stderr.write;
+
+    process.stdout.write = this.wrap(this.originalStdoutWrite, process.stdout);
+    process.stderr.write = this.wrap(this.originalStderrWrite, process.stderr);
+  }
+
+  public stop(): void {
+    if (this.originalStdoutWrite && this.originalStderrWrite) {
+      process.stdout.write = this.originalStdoutWrite;
+      process.stderr.write = this.originalStderrWrite;
+    }
+
+    this.originalStdoutWrite = null;
+    this.originalStderrWrite = null;
+  }
+
+  public getText(): string {
+    return this.chunks.join('');
+  }
+
+  private wrap(
+    write: typeof process.stdout.write,
+    stream: NodeJS.WriteStream,
+  ): typeof process.stdout.write {
+    return ((chunk: any, ...args: any[]) => {
+      this.chunks.push(
+        typeof chunk === 'string' ? chunk : Buffer.from(chunk).toString(),
+      );
+      return write.apply(stream, [chunk, ...args] as any);
+    }) as typeof process.stdout.write;
+  }
+}
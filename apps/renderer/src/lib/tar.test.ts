@@ -0,0 +1,98 @@
+import { describe, it, expect } from 'vitest';
+import { gunzipSync } from 'zlib';
+import { createTar, createTarGz } from './tar';
+
+type ReadEntry = { name: string; size: number; mtime: number; content: string };
+
+// Reads a ustar archive back, checking the header checksums on the way
+const readTar = (archive: Buffer): ReadEntry[] => {
+  const entries: ReadEntry[] = [];
+  let offset = 0;
+
+  while (offset < archive.length) {
+    const header = archive.subarray(offset, offset + 512);
+    if (header.every((byte) => byte === 0)) {
+      break;
+    }
+
+    const field = (start: number, size: number) =>
+      header
+        .subarray(start, start + size)
+        .toString('utf-8')
+        .replace(/\0.*$/s, '');
+    const octal = (start: number, size: number) =>
+      parseInt(field(start, size).trim(), 8);
+
+    const unsigned = Buffer.from(header);
+    unsigned.fill(' ', 148, 156);
+    const checksum = unsigned.reduce((total, byte) => total + byte, 0);
+    expect(octal(148, 8)).toBe(checksum);
+    expect(field(257, 6)).toBe('ustar');
+
+    const size = octal(124, 12);
+    entries.push({
+      name: field(0, 100),
+      size,
+      mtime: octal(136, 12),
+      content: archive
+        .subarray(offset + 512, offset + 512 + size)
+        .toString('utf-8'),
+    });
+    offset += 512 + Math.ceil(size / 512) * 512;
+  }
+
+  // End of archive: two empty blocks
+  expect(archive.length - offset).toBe(1024);
+  expect(archive.subarray(offset).every((byte) => byte === 0)).toBe(true);
+
+  return entries;
+};
+
+describe('Tar', () => {
+  const mtime = new Date('2025-03-14T09:26:53Z');
+
+  it('should write files in 512-byte blocks with valid headers', () => {
+    const archive = createTar([
+      { name: 'report/empty.txt', content: '', mtime },
+      { name: 'report/log.txt', content: 'x'.repeat(513), mtime },
+      { name: 'report/data.bin', content: Buffer.from('zażółć'), mtime },
+    ]);
+
+    expect(archive.length % 512).toBe(0);
+    expect(readTar(archive)).toEqual([
+      {
+        name: 'report/empty.txt',
+        size: 0,
+        mtime: mtime.getTime() / 1000,
+        content: '',
+      },
+      {
+        name: 'report/log.txt',
+        size: 513,
+        mtime: mtime.getTime() / 1000,
+        content: 'x'.repeat(513),
+      },
+      {
+        name: 'report/data.bin',
+        size: Buffer.byteLength('zażółć'),
+        mtime: mtime.getTime() / 1000,
+        content: 'zażółć',
+      },
+    ]);
+  });
+
+  it('should reject names that do not fit into the header', () => {
+    expect(() => createTar([{ name: 'a'.repeat(101), content: '' }])).toThrow(
+      'Tar entry name is too long',
+    );
+    expect(() =>
+      createTar([{ name: 'a'.repeat(100), content: '' }]),
+    ).not.toThrow();
+  });
+
+  it('should gzip the archive', () => {
+    const entries = [{ name: 'a.txt', content: 'hello', mtime }];
+
+    expect(gunzipSync(createTarGz(entries))).toEqual(createTar(entries));
+  });
+});
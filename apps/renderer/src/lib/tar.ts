@@ -0,0 +1,97 @@
+import { gzipSync } from 'zlib';
+
+export interface TarEntry {
+  name: string; // path inside the archive, e.g. "report/project.html"
+  content: Buffer | string;
+  mtime?: Date;
+}
+
+const BLOCK_SIZE = 512;
+
+/**
+ * Writes a string into a header field, truncated to the field size
+ */
+function writeString(
+  header: Buffer,
+  value: string,
+  offset: number,
+  size: number,
+): void {
+  header.write(value.slice(0, size), offset, size, 'utf-8');
+}
+
+/**
+ * Writes a number as a NUL-terminated octal string into a header field
+ */
+function writeOctal(
+  header: Buffer,
+  value: number,
+  offset: number,
+  size: number,
+): void {
+  writeString(header, value.toString(8).padStart(size - 1, '0'), offset, size);
+}
+
+/**
+ * Creates a 512-byte ustar header of a regular file
+ */
+function makeHeader(name: string, size: number, mtime: Date): Buffer {
+  if (Buffer.byteLength(name) > 100) {
+    throw new Error(`Tar entry name is too long: ${name}`);
+  }
+
+  const header = Buffer.alloc(BLOCK_SIZE);
+
+  writeString(header, name, 0, 100);
+  writeOctal(header, 0o644, 100, 8); // mode
+  writeOctal(header, 0, 108, 8); // uid
+  writeOctal(header, 0, 116, 8); // gid
+  writeOctal(header, size, 124, 12);
+  writeOctal(header, Math.floor(mtime.getTime() / 1000), 136, 12);
+  header.fill(' ', 148, 156); // checksum is calculated with spaces in place
+  writeString(header, '0', 156, 1); // regular file
+  writeString(header, 'ustar\0', 257, 6);
+  writeString(header, '00', 263, 2);
+
+  let checksum = 0;
+  for (const byte of header) {
+    checksum += byte;
+  }
+  writeString(header, checksum.toString(8).padStart(6, '0') + '\0 ', 148, 8);
+
+  return header;
+}
+
+/**
+ * Creates an uncompressed tar archive (ustar format) of the given files
+ */
+export function createTar(entries: TarEntry[]): Buffer {
+  const blocks: Buffer[] = [];
+
+  for (const entry of entries) {
+    const content = Buffer.isBuffer(entry.content)
+      ? entry.content
+      : Buffer.from(entry.content, 'utf-8');
+
+    blocks.push(makeHeader(entry.name, content.length, entry.mtime ?? new Date()));
+    blocks.push(content);
+
+    // Content is padded to full blocks
+    const padding = (BLOCK_SIZE - (content.length % BLOCK_SIZE)) % BLOCK_SIZE;
+    if (padding > 0) {
+      blocks.push(Buffer.alloc(padding));
+    }
+  }
+
+  // End of archive: two empty blocks
+  blocks.push(Buffer.alloc(BLOCK_SIZE * 2));
+
+  return Buffer.concat(blocks);
+}
+
+/**
+ * Creates a gzipped tar archive (.tar.gz) of the given files
+ */
+export function createTarGz(entries: TarEntry[]): Buffer {
+  return gzipSync(createTar(entries));
+}
@@ -0,0 +1,162 @@
+import { describe, it, expect } from 'vitest';
+import {
+  ProjectEditor,
+  applyEditCommand,
+  setDeclaration,
+  splitEditCommand,
+} from './project-editor';
+
+const PROJECT = `<project>
+  <sequence id="main">
+    <fragment id="intro" class="intro" />
+    <fragment class="broll" style="-duration: 2s" />
+    <gap duration="1s" />
+  </sequence>
+</project>
+
+<style>
+  .intro {
+    width: 100%;
+    -duration: 3s;
+  }
+</style>
+`;
+
+describe('ProjectEditor', () => {
+  describe('setStyle', () => {
+    it('should replace an existing declaration', () => {
+      const editor = new ProjectEditor(PROJECT);
+      editor.setStyle('.intro', 'width', '50%');
+
+      expect(editor.getSource()).toContain(
+        '    width: 50%;\n    -duration: 3s;',
+      );
+    });
+
+    it('should add a declaration to an existing rule', () => {
+      const editor = new ProjectEditor(PROJECT);
+      editor.setStyle('.intro', 'height', '20%');
+
+      expect(editor.getSource()).toContain(
+        '    -duration: 3s;\n    height: 20%;\n  }',
+      );
+    });
+
+    it('should add a rule if none matches the selector', () => {
+      const editor = new ProjectEditor(PROJECT);
+      editor.setStyle('.broll', '-duration', '4s');
+
+      expect(editor.getSource()).toContain(
+        '  .broll {\n    -duration: 4s;\n  }\n</style>',
+      );
+    });
+  });
+
+  describe('addFragment', () => {
+    it('should insert a fragment at a position', () => {
+      const editor = new ProjectEditor(PROJECT);
+      editor.addFragment('main', { className: 'broll', position: 2 });
+
+      expect(editor.getSource()).toContain(
+        '<fragment id="intro" class="intro" />\n    <fragment class="broll" />\n    <fragment class="broll" style',
+      );
+    });
+
+    it('should append a fragment to a sequence by number', () => {
+      const editor = new ProjectEditor(PROJECT);
+      editor.addFragment('1', { id: 'outro', asset: 'clip' });
+
+      expect(editor.getSource()).toContain(
+        '<gap duration="1s" />\n    <fragment id="outro" data-asset="clip" />\n  </sequence>',
+      );
+    });
+
+    it('should reject duplicate ids and unknown sequences', () => {
+      const editor = new ProjectEditor(PROJECT);
+
+      expect(() => editor.addFragment('main', { id: 'intro' })).toThrow(
+        'already exists',
+      );
+      expect(() => editor.addFragment('other')).toThrow('not found');
+    });
+  });
+
+  describe('retime', () => {
+    it('should set -duration in the inline style of a fragment', () => {
+      const editor = new ProjectEditor(PROJECT);
+      editor.retime('main:2', '4s');
+      editor.retime('intro', '1500ms');
+
+      expect(editor.getSource()).toContain('style="-duration: 4s;"');
+      expect(editor.getSource()).toContain(
+        '<fragment id="intro" class="intro" style="-duration: 1500ms;" />',
+      );
+    });
+
+    it('should set the duration attribute of a gap', () => {
+      const editor = new ProjectEditor(PROJECT);
+      editor.retime('main:3', '2s');
+
+      expect(editor.getSource()).toContain('<gap duration="2s" />');
+    });
+
+    it('should rewrite the duration attribute of a fragment', () => {
+      const editor = new ProjectEditor(
+        PROJECT.replace(
+          'class="broll" style="-duration: 2s"',
+          'duration="3s"',
+        ),
+      );
+      editor.retime('main:2', '4s');
+
+      expect(editor.getSource()).toContain('<fragment duration="4s" />');
+    });
+
+    it('should rewrite the duration of data-timing', () => {
+      const editor = new ProjectEditor(
+        PROJECT.replace(
+          'class="broll" style="-duration: 2s"',
+          'data-timing="ts=1s, d=3s, os=500"',
+        ),
+      );
+      editor.retime('main:2', '4s');
+
+      expect(editor.getSource()).toContain(
+        '<fragment data-timing="ts=1s, d=4s, os=500" />',
+      );
+      expect(editor.getSource()).not.toContain('style="-duration');
+    });
+
+    it('should reject invalid durations', () => {
+      const editor = new ProjectEditor(PROJECT);
+
+      expect(() => editor.retime('main:2', 'long')).toThrow(
+        'Invalid duration',
+      );
+    });
+  });
+
+  describe('applyEditCommand', () => {
+    it('should apply batch file lines', () => {
+      const editor = new ProjectEditor(PROJECT);
+      applyEditCommand(
+        editor,
+        splitEditCommand('add-fragment main --class "broll wide" --at 3'),
+      );
+
+      expect(editor.getSource()).toContain('<fragment class="broll wide" />');
+      expect(() => applyEditCommand(editor, ['rename', 'a'])).toThrow(
+        'Unknown edit command',
+      );
+    });
+  });
+
+  describe('setDeclaration', () => {
+    it('should replace or append a declaration', () => {
+      expect(
+        setDeclaration('-duration: 2s; width: 10%', '-duration', '4s'),
+      ).toBe('-duration: 4s; width: 10%;');
+      expect(setDeclaration('', '-duration', '4s')).toBe('-duration: 4s;');
+    });
+  });
+});
@@ -0,0 +1,605 @@
+import * as htmlparser2 from 'htmlparser2';
+import * as csstree from 'css-tree';
+import type { Element, AnyNode, Document } from 'domhandler';
+
+/**
+ * Edits a project file in place. Elements are located with the document
+ * model (htmlparser2 with source positions, css-tree for <style>), and only
+ * the affected ranges of the source are rewritten, so formatting and
+ * comments elsewhere stay untouched.
+ */
+export class ProjectEditor {
+  constructor(private source: string) {}
+
+  public getSource(): string {
+    return this.source;
+  }
+
+  /**
+   * Sets a property in the CSS rule with exactly this selector,
+   * a new rule is added to the last <style> if there is none
+   * @returns Description of the change
+   */
+  public setStyle(selector: string, property: string, value: string): string {
+    const document = this.parse();
+    const styles = findElements(document, 'style');
+    const normalizedSelector = normalizeWhitespace(selector);
+
+    for (const style of styles) {
+      const range = this.getContentRange(style);
+      if (!range) {
+        continue;
+      }
+
+      const css = this.source.slice(range.start, range.end);
+      const ast = csstree.parse(css, { positions: true });
+
+      const rules: csstree.Rule[] = [];
+      csstree.walk(ast, {
+        visit: 'Rule',
+        enter: (node) => {
+          const rule = node as csstree.Rule;
+          if (!rule.prelude.loc) {
+            return;
+          }
+          const prelude = css.slice(
+            rule.prelude.loc.start.offset,
+            rule.prelude.loc.end.offset,
+          );
+          if (normalizeWhitespace(prelude) === normalizedSelector) {
+            rules.push(rule);
+          }
+        },
+      });
+
+      const rule = rules[0];
+      if (rule) {
+        const edit = this.setRuleDeclaration(css, rule, property, value);
+        this.splice(range.start + edit.start, range.start + edit.end, edit.text);
+        return `${selector} { ${property}: ${value}; }`;
+      }
+    }
+
+    // No such rule: add one to the last <style>, or a new <style> at the end
+    const lastStyle = styles[styles.length - 1];
+    const ruleText = (indent: string) =>
+      `${indent}${selector} {\n${indent}  ${property}: ${value};\n${indent}}\n`;
+
+    if (lastStyle) {
+      const indent = this.getIndent(lastStyle.startIndex!);
+      const closingTag = this.source.lastIndexOf('</', lastStyle.endIndex!);
+      const lineStart = this.source.lastIndexOf('\n', closingTag - 1) + 1;
+      const insertAt = /^\s*$/.test(this.source.slice(lineStart, closingTag))
+        ? lineStart
+        : closingTag;
+      this.splice(
+        insertAt,
+        insertAt,
+        (insertAt === closingTag ? '\n' : '') + ruleText(indent + '  '),
+      );
+    } else {
+      this.source =
+        this.source.replace(/\s*$/, '\n') +
+        `<style>\n${ruleText('  ')}</style>\n`;
+    }
+
+    return `${selector} { ${property}: ${value}; } (new rule)`;
+  }
+
+  /**
+   * Adds a fragment to a sequence
+   * @param sequenceRef - Sequence id or 1-based number
+   * @param position - 1-based position among the fragments/gaps, appended if not set
+   * @returns Description of the change
+   */
+  public addFragment(
+    sequenceRef: string,
+    options: {
+      id?: string;
+      className?: string;
+      asset?: string;
+      style?: string;
+      position?: number;
+    } = {},
+  ): string {
+    const document = this.parse();
+    const sequence = this.findSequence(document, sequenceRef);
+    const items = getTimelineItems(sequence);
+
+    if (options.id && findElementById(document, options.id)) {
+      throw new Error(`An element with id="${options.id}" already exists`);
+    }
+
+    const position = options.position ?? items.length + 1;
+    if (position < 1 || position > items.length + 1) {
+      throw new Error(
+        `Position ${position} is out of range, sequence "${sequenceRef}" has ${items.length} fragment(s)`,
+      );
+    }
+
+    const attributes = [
+      options.id && `id="${escapeAttribute(options.id)}"`,
+      options.className && `class="${escapeAttribute(options.className)}"`,
+      options.asset && `data-asset="${escapeAttribute(options.asset)}"`,
+      options.style && `style="${escapeAttribute(options.style)}"`,
+    ].filter(Boolean);
+    const fragmentText = `<fragment${attributes.map((attribute) => ` ${attribute}`).join('')} />`;
+
+    if (position <= items.length) {
+      // Before an existing fragment, on its own line
+      const next = items[position - 1];
+      const indent = this.getIndent(next.startIndex!);
+      this.splice(
+        next.startIndex!,
+        next.startIndex!,
+        `${fragmentText}\n${indent}`,
+      );
+    } else if (items.length > 0) {
+      // After the last fragment
+      const last = items[items.length - 1];
+      const indent = this.getIndent(last.startIndex!);
+      this.splice(
+        last.endIndex! + 1,
+        last.endIndex! + 1,
+        `\n${indent}${fragmentText}`,
+      );
+    } else {
+      // Empty sequence
+      const indent = this.getIndent(sequence.startIndex!);
+      const openingTagEnd = this.getOpeningTagEnd(sequence);
+      const openingTag = this.source.slice(sequence.startIndex!, openingTagEnd);
+
+      if (openingTag.endsWith('/>')) {
+        this.splice(
+          sequence.startIndex!,
+          openingTagEnd,
+          `${openingTag.replace(/\s*\/>$/, '>')}\n${indent}  ${fragmentText}\n${indent}</sequence>`,
+        );
+      } else {
+        const closingTag = this.source.lastIndexOf('</', sequence.endIndex!);
+        this.splice(
+          openingTagEnd,
+          closingTag,
+          `\n${indent}  ${fragmentText}\n${indent}`,
+        );
+      }
+    }
+
+    return `${fragmentText} added to sequence "${sequenceRef}" at position ${position}`;
+  }
+
+  /**
+   * Sets the duration of a gap or a fragment where the parser reads it from:
+   * the duration attribute, then the "d" of data-timing, then inline -duration
+   * @param fragmentRef - "main:2" (sequence id or number : fragment number or id) or a fragment id
+   * @param duration - e.g. "4s", "1500ms"
+   * @returns Description of the change
+   */
+  public retime(fragmentRef: string, duration: string): string {
+    if (!/^\d+(?:\.\d+)?(?:ms|s)$/.test(duration)) {
+      throw new Error(
+        `Invalid duration "${duration}", use e.g. "4s" or "1500ms"`,
+      );
+    }
+
+    const document = this.parse();
+    const element = this.findTimelineItem(document, fragmentRef);
+
+    if (element.name === 'gap' || element.attribs.duration !== undefined) {
+      this.setAttribute(element, 'duration', duration);
+      return `<${element.name}> ${fragmentRef}: duration="${duration}"`;
+    }
+
+    const timing = element.attribs['data-timing'];
+    if (timing !== undefined && /(?:^|,)\s*d\s*=/.test(timing)) {
+      this.setAttribute(
+        element,
+        'data-timing',
+        timing
+          .split(',')
+          .map((pair) =>
+            pair.trim().split('=')[0].trim() === 'd'
+              ? pair.replace(/=.*$/, `=${duration}`)
+              : pair,
+          )
+          .join(','),
+      );
+      return `<fragment> ${fragmentRef}: data-timing d=${duration}`;
+    }
+
+    const style = element.attribs.style ?? '';
+    this.setAttribute(
+      element,
+      'style',
+      setDeclaration(style, '-duration', duration),
+    );
+    return `<fragment> ${fragmentRef}: -duration: ${duration}`;
+  }
+
+  private parse(): Document {
+    return htmlparser2.parseDocument(this.source, {
+      xmlMode: true,
+      lowerCaseTags: false,
+      lowerCaseAttributeNames: false,
+      withStartIndices: true,
+      withEndIndices: true,
+    });
+  }
+
+  private splice(start: number, end: number, text: string): void {
+    this.source = this.source.slice(0, start) + text + this.source.slice(end);
+  }
+
+  /**
+   * Leading whitespace of the line the index is on
+   */
+  private getIndent(index: number): string {
+    const lineStart = this.source.lastIndexOf('\n', index - 1) + 1;
+    return this.source.slice(lineStart).match(/^[ \t]*/)![0];
+  }
+
+  /**
+   * Index right after the ">" of an element's opening tag
+   */
+  private getOpeningTagEnd(element: Element): number {
+    let quote: string | null = null;
+    for (let i = element.startIndex!; i < this.source.length; i++) {
+      const char = this.source[i];
+      if (quote) {
+        if (char === quote) {
+          quote = null;
+        }
+      } else if (char === '"' || char === "'") {
+        quote = char;
+      } else if (char === '>') {
+        return i + 1;
+      }
+    }
+    throw new Error(`Unterminated <${element.name}> tag`);
+  }
+
+  /**
+   * Source range of an element's content (between its tags)
+   */
+  private getContentRange(
+    element: Element,
+  ): { start: number; end: number } | null {
+    const first = element.children[0];
+    const last = element.children[element.children.length - 1];
+    if (!first || !last) {
+      return null;
+    }
+    return { start: first.startIndex!, end: last.endIndex! + 1 };
+  }
+
+  private setAttribute(element: Element, name: string, value: string): void {
+    const start = element.startIndex!;
+    const end = this.getOpeningTagEnd(element);
+    const openingTag = this.source.slice(start, end);
+    const attribute = `${name}="${escapeAttribute(value)}"`;
+
+    const existing = new RegExp(
+      `(\\s)${name}\\s*=\\s*("[^"]*"|'[^']*')`,
+    ).exec(openingTag);
+    if (existing) {
+      const attributeStart = start + existing.index + existing[1].length;
+      this.splice(
+        attributeStart,
+        start + existing.index + existing[0].length,
+        attribute,
+      );
+      return;
+    }
+
+    // Before "/>" or ">"
+    const tagClose = openingTag.endsWith('/>') ? end - 2 : end - 1;
+    let insertAt = tagClose;
+    while (insertAt > start && /\s/.test(this.source[insertAt - 1])) {
+      insertAt--;
+    }
+    this.splice(insertAt, insertAt, ` ${attribute}`);
+  }
+
+  /**
+   * Sets a declaration in a rule: replaces the existing one or adds it
+   * after the last declaration, following the rule's formatting
+   */
+  private setRuleDeclaration(
+    css: string,
+    rule: csstree.Rule,
+    property: string,
+    value: string,
+  ): { start: number; end: number; text: string } {
+    const declarations = rule.block.children
+      .toArray()
+      .filter(
+        (node): node is csstree.Declaration => node.type === 'Declaration',
+      );
+    const declarationText = `${property}: ${value}`;
+
+    const existing = declarations
+      .filter(
+        (declaration) =>
+          declaration.property.toLowerCase() === property.toLowerCase(),
+      )
+      .pop();
+    if (existing?.loc) {
+      return {
+        start: existing.loc.start.offset,
+        end: existing.loc.end.offset,
+        text: declarationText,
+      };
+    }
+
+    const blockLoc = rule.block.loc!;
+    const closingBrace = blockLoc.end.offset - 1;
+    const isMultiline = css
+      .slice(blockLoc.start.offset, blockLoc.end.offset)
+      .includes('\n');
+
+    const last = declarations[declarations.length - 1];
+    if (!last?.loc) {
+      const ruleIndent = css
+        .slice(css.lastIndexOf('\n', blockLoc.start.offset) + 1)
+        .match(/^[ \t]*/)![0];
+      return {
+        start: blockLoc.start.offset + 1,
+        end: closingBrace,
+        text: isMultiline
+          ? `\n${ruleIndent}  ${declarationText};\n${ruleIndent}`
+          : ` ${declarationText}; `,
+      };
+    }
+
+    const afterLast = last.loc.end.offset;
+    const semicolon = css.slice(afterLast, closingBrace).indexOf(';');
+    const insertAt = semicolon >= 0 ? afterLast + semicolon + 1 : afterLast;
+    const lastIndent = css
+      .slice(css.lastIndexOf('\n', last.loc.start.offset) + 1)
+      .match(/^[ \t]*/)![0];
+
+    return {
+      start: insertAt,
+      end: insertAt,
+      text:
+        (semicolon >= 0 ? '' : ';') +
+        (isMultiline
+          ? `\n${lastIndent}${declarationText};`
+          : ` ${declarationText};`),
+    };
+  }
+
+  /**
+   * Sequences are the direct <sequence> children of <project>, like in the parser
+   */
+  private getSequences(document: Document): Element[] {
+    const project = findElements(document, 'project')[0];
+    if (!project) {
+      throw new Error('No <project> element found');
+    }
+    return project.children.filter(
+      (child): child is Element =>
+        child.type === 'tag' && (child as Element).name === 'sequence',
+    );
+  }
+
+  private findSequence(document: Document, sequenceRef: string): Element {
+    const sequences = this.getSequences(document);
+    const byId = sequences.find(
+      (sequence) => sequence.attribs.id === sequenceRef,
+    );
+    const byNumber = /^\d+$/.test(sequenceRef)
+      ? sequences[parseInt(sequenceRef, 10) - 1]
+      : undefined;
+
+    const sequence = byId ?? byNumber;
+    if (!sequence) {
+      throw new Error(`Sequence "${sequenceRef}" not found`);
+    }
+    return sequence;
+  }
+
+  /**
+   * Resolves "main:2", "1:2", "main:intro" or "intro" (same format as explain)
+   */
+  private findTimelineItem(document: Document, ref: string): Element {
+    const separator = ref.lastIndexOf(':');
+    const sequenceRef = separator >= 0 ? ref.slice(0, separator) : '';
+    const itemRef = separator >= 0 ? ref.slice(separator + 1) : ref;
+
+    const sequences = sequenceRef
+      ? [this.findSequence(document, sequenceRef)]
+      : this.getSequences(document);
+
+    for (const sequence of sequences) {
+      const items = getTimelineItems(sequence);
+
+      const byId = items.find((item) => item.attribs.id === itemRef);
+      if (byId) {
+        return byId;
+      }
+
+      // Numbers are 1-based positions, only when the sequence is explicit
+      if (sequenceRef && /^\d+$/.test(itemRef)) {
+        const byNumber = items[parseInt(itemRef, 10) - 1];
+        if (byNumber) {
+          return byNumber;
+        }
+      }
+    }
+
+    throw new Error(`Fragment "${ref}" not found`);
+  }
+}
+
+/**
+ * Applies one edit command, e.g. from a batch file:
+ *   set-style <selector> <property> <value...>
+ *   add-fragment <sequence> [--id <id>] [--class <names>] [--asset <name>] [--style <css>] [--at <n>]
+ *   retime <fragment> <duration>
+ * @returns Description of the change
+ */
+export function applyEditCommand(
+  editor: ProjectEditor,
+  args: string[],
+): string {
+  const [command, ...rest] = args;
+
+  switch (command) {
+    case 'set-style': {
+      const [selector, property, ...value] = rest;
+      if (!selector || !property || value.length === 0) {
+        throw new Error('Usage: set-style <selector> <property> <value>');
+      }
+      return editor.setStyle(selector, property, value.join(' '));
+    }
+    case 'add-fragment': {
+      const [sequenceRef, ...flags] = rest;
+      if (!sequenceRef) {
+        throw new Error(
+          'Usage: add-fragment <sequence> [--id <id>] [--class <names>] [--asset <name>] [--style <css>] [--at <n>]',
+        );
+      }
+
+      const options: Parameters<ProjectEditor['addFragment']>[1] = {};
+      for (let i = 0; i < flags.length; i += 2) {
+        const value = flags[i + 1];
+        if (value === undefined) {
+          throw new Error(`Missing value of ${flags[i]}`);
+        }
+        switch (flags[i]) {
+          case '--id':
+            options.id = value;
+            break;
+          case '--class':
+            options.className = value;
+            break;
+          case '--asset':
+            options.asset = value;
+            break;
+          case '--style':
+            options.style = value;
+            break;
+          case '--at':
+            options.position = parsePosition(value);
+            break;
+          default:
+            throw new Error(`Unknown add-fragment option ${flags[i]}`);
+        }
+      }
+      return editor.addFragment(sequenceRef, options);
+    }
+    case 'retime': {
+      const [fragmentRef, duration] = rest;
+      if (!fragmentRef || !duration) {
+        throw new Error('Usage: retime <fragment> <duration>');
+      }
+      return editor.retime(fragmentRef, duration);
+    }
+    default:
+      throw new Error(`Unknown edit command "${command}"`);
+  }
+}
+
+/**
+ * Splits a batch file line into arguments, quotes group words
+ */
+export function splitEditCommand(line: string): string[] {
+  return (
+    line
+      .match(/(?:[^\s"']+|"[^"]*"|'[^']*')+/g)
+      ?.map((arg) => arg.replace(/^"(.*)"$|^'(.*)'$/, '$1$2')) ?? []
+  );
+}
+
+export function parsePosition(value: string): number {
+  const position = parseInt(value, 10);
+  if (!/^\d+$/.test(value) || position < 1) {
+    throw new Error(`Invalid position "${value}", expected 1 or more`);
+  }
+  return position;
+}
+
+/**
+ * Updates a declaration in an inline style: replaced if present, appended otherwise
+ */
+export function setDeclaration(
+  style: string,
+  property: string,
+  value: string,
+): string {
+  const declarations = style
+    .split(';')
+    .map((declaration) => declaration.trim())
+    .filter(Boolean);
+
+  const index = declarations.findIndex(
+    (declaration) =>
+      declaration.split(':')[0].trim().toLowerCase() === property.toLowerCase(),
+  );
+  const declaration = `${property}: ${value}`;
+  if (index >= 0) {
+    declarations[index] = declaration;
+  } else {
+    declarations.push(declaration);
+  }
+
+  return declarations.join('; ') + ';';
+}
+
+function normalizeWhitespace(value: string): string {
+  return value.replace(/\s+/g, ' ').replace(/\s*,\s*/g, ', ').trim();
+}
+
+function escapeAttribute(value: string): string {
+  return value.replace(/&/g, '&amp;').replace(/"/g, '&quot;');
+}
+
+function findElements(root: AnyNode, name: string): Element[] {
+  const results: Element[] = [];
+
+  const traverse = (node: AnyNode) => {
+    if (node.type === 'tag' && (node as Element).name === name) {
+      results.push(node as Element);
+    }
+    if ('children' in node && node.children) {
+      for (const child of node.children) {
+        traverse(child);
+      }
+    }
+  };
+
+  traverse(root);
+  return results;
+}
+
+function findElementById(root: AnyNode, id: string): Element | undefined {
+  let result: Element | undefined;
+
+  const traverse = (node: AnyNode) => {
+    if (result) {
+      return;
+    }
+    if (node.type === 'tag' && (node as Element).attribs.id === id) {
+      result = node as Element;
+      return;
+    }
+    if ('children' in node && node.children) {
+      for (const child of node.children) {
+        traverse(child);
+      }
+    }
+  };
+
+  traverse(root);
+  return result;
+}
+
+/**
+ * Fragments and gaps of a sequence, in order
+ */
+function getTimelineItems(sequence: Element): Element[] {
+  return findElements(sequence, 'fragment')
+    .concat(findElements(sequence, 'gap'))
+    .sort((a, b) => a.startIndex! - b.startIndex!);
+}
@@ -6,17 +6,30 @@ import {
   Upload,
   AIProvider,
   SequenceDebugInfo,
+  Chapter,
+  Assertion,
+  MarkerTime,
+  Fragment,
+  Font,
 } from './type';
-import { Label } from './ffmpeg';
+import { Label, makeScale, makeSplit } from './ffmpeg';
 import { AssetManager } from './asset-manager';
 import { Sequence } from './sequence';
 import { FilterBuffer } from './stream';
 import { ExpressionContext, FragmentData } from './expression-parser';
-import { renderContainers } from './container-renderer';
+import { getRootFontSize, renderContainers } from './container-renderer';
 import { renderApp } from './app-renderer';
 import puppeteer from 'puppeteer';
 import { buildAppsIfNeeded } from './app-builder';
 import { dirname } from 'path';
+import { getFanOutPrimary } from './fan-out';
+import { formatChapterTime } from './time-utils';
+import {
+  findMissingGlyphs,
+  getHtmlText,
+  makeFontFaceCss,
+  resolveFonts,
+} from './fonts';
 
 export class Project {
   private assetManager: AssetManager;
@@ -35,6 +48,8 @@ export class Project {
     private tags: string[],
     private cssText: string,
     private projectPath: string,
+    private assertions: Assertion[] = [],
+    private fonts: Font[] = [],
   ) {
     this.assetManager = new AssetManager(assets);
     this.expressionContext = {
@@ -97,6 +112,137 @@ export class Project {
     return buf;
   }
 
+  /**
+   * Builds the filter graph once, at the largest of the given outputs
+   * (same aspect ratio and fps, see groupOutputsForFanOut), and fans it out:
+   * the composite is split and downscaled per output.
+   * The n-th output ends at outv_<n> and outa_<n>.
+   */
+  public async buildFanOut(outputNames: string[]): Promise<FilterBuffer> {
+    const outputs = outputNames.map((outputName) => {
+      const output = this.getOutput(outputName);
+      if (!output) {
+        throw new Error(`Output "${outputName}" not found`);
+      }
+      return output;
+    });
+
+    const primary = getFanOutPrimary(outputs);
+    const buf = await this.build(primary.name);
+
+    const videoSplit = makeSplit(
+      [{ tag: 'outv', isAudio: false }],
+      outputs.length,
+    );
+    const audioSplit = makeSplit(
+      [{ tag: 'outa', isAudio: true }],
+      outputs.length,
+    );
+    buf.append(videoSplit);
+    buf.append(audioSplit);
+
+    outputs.forEach((output, index) => {
+      audioSplit.outputs[index] = { tag: `outa_${index}`, isAudio: true };
+
+      const { width, height } = output.resolution;
+      if (
+        width === primary.resolution.width &&
+        height === primary.resolution.height
+      ) {
+        videoSplit.outputs[index] = { tag: `outv_${index}`, isAudio: false };
+        return;
+      }
+
+      const scaleRes = makeScale([videoSplit.outputs[index]], {
+        width,
+        height,
+        flags: 'flags=lanczos',
+      });
+      scaleRes.outputs[0] = { tag: `outv_${index}`, isAudio: false };
+      buf.append(scaleRes);
+    });
+
+    return buf;
+  }
+
+  /**
+   * Builds a standalone filter graph of a few adjacent fragments of a sequence
+   * (a single fragment, or two fragments around a transition).
+   * The whole project is built first, so calc() expressions resolve to the
+   * same values as in the full render.
+   * Returns the filter graph and an asset manager with just the used inputs.
+   */
+  public async buildFragments(
+    outputName: string,
+    sequenceIndex: number,
+    fragmentIndices: number[],
+  ): Promise<{ buf: FilterBuffer; assetManager: AssetManager }> {
+    const output = this.getOutput(outputName);
+    if (!output) {
+      throw new Error(`Output "${outputName}" not found`);
+    }
+
+    const sequenceDefinition = this.sequencesDefinitions[sequenceIndex];
+    if (!sequenceDefinition) {
+      throw new Error(`Sequence ${sequenceIndex + 1} not found`);
+    }
+
+    await this.build(outputName);
+
+    const fragments = fragmentIndices.map((fragmentIndex, index) => {
+      const fragment = sequenceDefinition.fragments[fragmentIndex];
+      if (!fragment) {
+        throw new Error(
+          `Fragment ${fragmentIndex + 1} not found in sequence ${sequenceIndex + 1}`,
+        );
+      }
+      // the first fragment starts the standalone sequence
+      return index === 0 ? { ...fragment, overlayLeft: 0 } : fragment;
+    });
+
+    // Only the assets of the selected fragments become inputs,
+    // sub-clips need their source to share its input
+    const assets: Asset[] = [];
+    const addAsset = (asset: Asset | undefined) => {
+      if (asset && !assets.includes(asset)) {
+        assets.push(asset);
+      }
+    };
+    for (const fragment of fragments) {
+      const asset = this.assetManager.getAssetByName(fragment.assetName);
+      if (asset?.subclip) {
+        addAsset(this.assetManager.getAssetByName(asset.subclip.from));
+      }
+      addAsset(asset);
+    }
+    const assetManager = new AssetManager(assets);
+
+    const buf = new FilterBuffer();
+    const seq = new Sequence(
+      buf,
+      { ...sequenceDefinition, fragments },
+      output,
+      assetManager,
+      // copy, so the project's timeline stays intact
+      { fragments: new Map(this.expressionContext.fragments) },
+    );
+    if (seq.isEmpty()) {
+      throw new Error('Selected fragments have nothing to render');
+    }
+
+    seq.build();
+    seq.getVideoStream().endTo({
+      tag: 'outv',
+      isAudio: false,
+    });
+    seq.getAudioStream().endTo({
+      tag: 'outa',
+      isAudio: true,
+    });
+
+    return { buf, assetManager };
+  }
+
   public printStats() {
     console.log('\n=== Project stats ===\n');
     console.log('== Assets ==\n');
@@ -150,6 +296,13 @@ export class Project {
     console.log('===========================================\n');
   }
 
+  /**
+   * Timeline of the last build() (sequences and fragments with resolved times)
+   */
+  public getDebugInfo(): SequenceDebugInfo[] {
+    return this.sequencesDebugInfo;
+  }
+
   public getAssetManager(): AssetManager {
     return this.assetManager;
   }
@@ -211,21 +364,24 @@ export class Project {
     return this.cssText;
   }
 
+  public getFonts(): Font[] {
+    return this.fonts;
+  }
+
   /**
-   * Collects timecodes from fragments with timecodeLabel
-   * Returns formatted timecodes in YouTube format (MM:SS or HH:MM:SS Label)
+   * Collects chapters from fragments with timecodeLabel, sorted by time
    * Note: This must be called after build() to have accurate times in expressionContext
    */
-  public getTimecodes(): string[] {
-    const timecodes: Array<{ time: number; label: string }> = [];
+  public getChapters(): Chapter[] {
+    const chapters: Chapter[] = [];
 
     // Collect all fragments with timecode labels
     for (const seqDef of this.sequencesDefinitions) {
       for (const fragment of seqDef.fragments) {
         if (fragment.timecodeLabel && this.expressionContext.fragments.has(fragment.id)) {
           const fragmentData = this.expressionContext.fragments.get(fragment.id)!;
-          timecodes.push({
-            time: fragmentData.time.start / 1000, // Convert ms to seconds
+          chapters.push({
+            time: fragmentData.time.start,
             label: fragment.timecodeLabel,
           });
         }
@@ -233,29 +389,167 @@ export class Project {
     }
 
     // Sort by time
-    timecodes.sort((a, b) => a.time - b.time);
+    return chapters.sort((a, b) => a.time - b.time);
+  }
 
-    // Format as YouTube timecodes (MM:SS or HH:MM:SS)
-    return timecodes.map(({ time, label }) => {
-      const hours = Math.floor(time / 3600);
-      const minutes = Math.floor((time % 3600) / 60);
-      const seconds = Math.floor(time % 60);
+  /**
+   * Collects timecodes from fragments with timecodeLabel
+   * Returns formatted timecodes in YouTube format (MM:SS or HH:MM:SS Label)
+   * Note: This must be called after build() to have accurate times in expressionContext
+   */
+  public getTimecodes(): string[] {
+    return this.getChapters().map(
+      ({ time, label }) => `${formatChapterTime(time)} ${label}`,
+    );
+  }
 
-      let timeStr: string;
-      if (hours > 0) {
-        timeStr = `${hours}:${minutes.toString().padStart(2, '0')}:${seconds.toString().padStart(2, '0')}`;
-      } else {
-        timeStr = `${minutes}:${seconds.toString().padStart(2, '0')}`;
+  public getAssertions(): Assertion[] {
+    return this.assertions;
+  }
+
+  /**
+   * Finds sequences by id or 1-based number, all sequences if no reference
+   * is given
+   */
+  public findSequences(sequenceRef?: string): SequenceDefinition[] {
+    if (sequenceRef === undefined) {
+      return this.sequencesDefinitions;
+    }
+
+    const byId = this.sequencesDefinitions.filter(
+      (sequence) => sequence.id === sequenceRef,
+    );
+    if (byId.length > 0) {
+      return byId;
+    }
+
+    const index = /^\d+$/.test(sequenceRef) ? parseInt(sequenceRef, 10) - 1 : -1;
+    const byNumber = this.sequencesDefinitions[index];
+    return byNumber ? [byNumber] : [];
+  }
+
+  /**
+   * Enabled fragments on screen at the given time (ms)
+   * Note: This must be called after build() to have accurate times in expressionContext
+   */
+  public getFragmentsAt(time: number, sequenceRef?: string): Fragment[] {
+    return this.findSequences(sequenceRef).flatMap((sequence) =>
+      sequence.fragments.filter((fragment) => {
+        const fragmentData = this.expressionContext.fragments.get(fragment.id);
+        return (
+          fragment.enabled &&
+          fragmentData !== undefined &&
+          fragmentData.time.start <= time &&
+          time < fragmentData.time.end
+        );
+      }),
+    );
+  }
+
+  /**
+   * End of the last enabled fragment of the given sequences (ms)
+   * Note: This must be called after build() to have accurate times in expressionContext
+   */
+  public getTimelineDuration(sequenceRef?: string): number {
+    let duration = 0;
+    for (const sequence of this.findSequences(sequenceRef)) {
+      for (const fragment of sequence.fragments) {
+        const fragmentData = this.expressionContext.fragments.get(fragment.id);
+        if (fragment.enabled && fragmentData) {
+          duration = Math.max(duration, fragmentData.time.end);
+        }
       }
+    }
+    return duration;
+  }
 
-      return `${timeStr} ${label}`;
-    });
+  /**
+   * Times of the <marker> elements, sorted by time. A marker is at the start
+   * of the fragment that follows it, or at the end of its sequence.
+   * Note: This must be called after build() to have accurate times in expressionContext
+   */
+  public getMarkers(): MarkerTime[] {
+    const markers: MarkerTime[] = [];
+
+    for (const sequence of this.sequencesDefinitions) {
+      for (const { name, fragmentIndex } of sequence.markers ?? []) {
+        // the start of the next fragment that is on the timeline
+        let time: number | undefined;
+        for (const fragment of sequence.fragments.slice(fragmentIndex)) {
+          const fragmentData = this.expressionContext.fragments.get(
+            fragment.id,
+          );
+          if (fragment.enabled && fragmentData) {
+            time = fragmentData.time.start;
+            break;
+          }
+        }
+
+        // or the end of the sequence
+        if (time === undefined) {
+          time = 0;
+          for (const fragment of sequence.fragments) {
+            const fragmentData = this.expressionContext.fragments.get(
+              fragment.id,
+            );
+            if (fragment.enabled && fragmentData) {
+              time = Math.max(time, fragmentData.time.end);
+            }
+          }
+        }
+
+        markers.push({ name, time });
+      }
+    }
+
+    return markers.sort((a, b) => a.time - b.time);
+  }
+
+  /**
+   * Time range between two markers (ms)
+   * Note: This must be called after build() to have accurate times in expressionContext
+   */
+  public getMarkerRange(
+    from: string,
+    to: string,
+  ): { start: number; end: number } {
+    const markers = this.getMarkers();
+    const find = (name: string) => {
+      const marker = markers.find((marker) => marker.name === name);
+      if (!marker) {
+        const available = markers.map((marker) => marker.name);
+        throw new Error(
+          `Marker "${name}" not found. ${available.length > 0 ? `Available markers: ${available.join(', ')}` : 'The project has no <marker> elements'}`,
+        );
+      }
+      return marker.time;
+    };
+
+    const start = find(from);
+    const end = find(to);
+    if (end <= start) {
+      throw new Error(
+        `Marker "${to}" (${Math.round(end)}ms) must come after "${from}" (${Math.round(start)}ms)`,
+      );
+    }
+
+    return { start, end };
   }
 
   public getSequenceDefinitions(): SequenceDefinition[] {
     return this.sequencesDefinitions;
   }
 
+  /**
+   * Whether any fragment has a container or an app, which are rendered
+   * per output resolution
+   */
+  public hasContainersOrApps(): boolean {
+    return this.sequencesDefinitions.some((seq) =>
+      seq.fragments.some((frag) => frag.container || frag.app),
+    );
+  }
+
   // Delegation methods for convenience
   public getAssetIndexMap(): Map<string, number> {
     return this.assetManager.getAssetIndexMap();
@@ -407,14 +701,31 @@ export class Project {
     const containers = fragmentsWithContainers.map((frag) => frag.container!);
     const projectDir = dirname(this.projectPath);
 
+    // The declared fonts are embedded into the page CSS, so they are a part
+    // of the container cache key
+    const fonts = await resolveFonts(this.fonts, projectDir);
+    for (const container of containers) {
+      const missing = findMissingGlyphs(
+        getHtmlText(container.htmlContent),
+        fonts,
+      );
+      if (missing.length > 0) {
+        console.warn(
+          `⚠️  No font in <fonts> has glyphs for ${missing.map((character) => `"${character}"`).join(', ')} of container "${container.id}", they are drawn with a fallback font of this machine`,
+        );
+      }
+    }
+    const fontFaceCss = await makeFontFaceCss(fonts);
+
     const results = await renderContainers(
       containers,
-      this.cssText,
+      fontFaceCss ? `${fontFaceCss}\n${this.cssText}` : this.cssText,
       output.resolution.width,
       output.resolution.height,
       projectDir,
       outputName,
       activeCacheKeys,
+      getRootFontSize(output),
     );
 
     // Create virtual assets and update fragment assetNames
@@ -0,0 +1,207 @@
+import { Scrim } from './type';
+
+export const DEFAULT_SCRIM: Scrim = {
+  type: 'gradient',
+  color: '#000000',
+  opacity: 0.6,
+  direction: 'bottom',
+  extent: 50,
+};
+
+const HEX_COLOR = /^#(?:[0-9a-f]{3,4}|[0-9a-f]{6}|[0-9a-f]{8})$/i;
+
+// rgb(0, 0, 0), rgba(0,0,0,0.5), rgb(0 0 0 / 50%), hsl(220deg 40% 20%)
+const COLOR_FUNCTION =
+  /^(?:rgba?|hsla?)\(\s*[-+\w.%]+(?:\s*[,/\s]\s*[-+\w.%]+){2,3}\s*\)$/i;
+
+// CSS named colors
+const COLOR_NAMES = new Set([
+  'aliceblue',
+  'antiquewhite',
+  'aqua',
+  'aquamarine',
+  'azure',
+  'beige',
+  'bisque',
+  'black',
+  'blanchedalmond',
+  'blue',
+  'blueviolet',
+  'brown',
+  'burlywood',
+  'cadetblue',
+  'chartreuse',
+  'chocolate',
+  'coral',
+  'cornflowerblue',
+  'cornsilk',
+  'crimson',
+  'cyan',
+  'darkblue',
+  'darkcyan',
+  'darkgoldenrod',
+  'darkgray',
+  'darkgreen',
+  'darkgrey',
+  'darkkhaki',
+  'darkmagenta',
+  'darkolivegreen',
+  'darkorange',
+  'darkorchid',
+  'darkred',
+  'darksalmon',
+  'darkseagreen',
+  'darkslateblue',
+  'darkslategray',
+  'darkslategrey',
+  'darkturquoise',
+  'darkviolet',
+  'deeppink',
+  'deepskyblue',
+  'dimgray',
+  'dimgrey',
+  'dodgerblue',
+  'firebrick',
+  'floralwhite',
+  'forestgreen',
+  'fuchsia',
+  'gainsboro',
+  'ghostwhite',
+  'gold',
+  'goldenrod',
+  'gray',
+  'green',
+  'greenyellow',
+  'grey',
+  'honeydew',
+  'hotpink',
+  'indianred',
+  'indigo',
+  'ivory',
+  'khaki',
+  'lavender',
+  'lavenderblush',
+  'lawngreen',
+  'lemonchiffon',
+  'lightblue',
+  'lightcoral',
+  'lightcyan',
+  'lightgoldenrodyellow',
+  'lightgray',
+  'lightgreen',
+  'lightgrey',
+  'lightpink',
+  'lightsalmon',
+  'lightseagreen',
+  'lightskyblue',
+  'lightslategray',
+  'lightslategrey',
+  'lightsteelblue',
+  'lightyellow',
+  'lime',
+  'limegreen',
+  'linen',
+  'magenta',
+  'maroon',
+  'mediumaquamarine',
+  'mediumblue',
+  'mediumorchid',
+  'mediumpurple',
+  'mediumseagreen',
+  'mediumslateblue',
+  'mediumspringgreen',
+  'mediumturquoise',
+  'mediumvioletred',
+  'midnightblue',
+  'mintcream',
+  'mistyrose',
+  'moccasin',
+  'navajowhite',
+  'navy',
+  'oldlace',
+  'olive',
+  'olivedrab',
+  'orange',
+  'orangered',
+  'orchid',
+  'palegoldenrod',
+  'palegreen',
+  'paleturquoise',
+  'palevioletred',
+  'papayawhip',
+  'peachpuff',
+  'peru',
+  'pink',
+  'plum',
+  'powderblue',
+  'purple',
+  'rebeccapurple',
+  'red',
+  'rosybrown',
+  'royalblue',
+  'saddlebrown',
+  'salmon',
+  'sandybrown',
+  'seagreen',
+  'seashell',
+  'sienna',
+  'silver',
+  'skyblue',
+  'slateblue',
+  'slategray',
+  'slategrey',
+  'snow',
+  'springgreen',
+  'steelblue',
+  'tan',
+  'teal',
+  'thistle',
+  'tomato',
+  'turquoise',
+  'violet',
+  'wheat',
+  'white',
+  'whitesmoke',
+  'yellow',
+  'yellowgreen',
+]);
+
+/**
+ * Whether the value is a color a scrim accepts: hex, rgb()/rgba(),
+ * hsl()/hsla() or a CSS color name. Anything else is most likely a typo
+ * of another scrim value, e.g. "bottm".
+ */
+export function isScrimColor(value: string): boolean {
+  return (
+    HEX_COLOR.test(value) || COLOR_FUNCTION.test(value) || isColorName(value)
+  );
+}
+
+/**
+ * Whether the value is a CSS color name, e.g. "navy"
+ */
+export function isColorName(value: string): boolean {
+  return COLOR_NAMES.has(value.toLowerCase());
+}
+
+// The gradient starts at the darkest edge
+const GRADIENT_DIRECTIONS: Record<Scrim['direction'], string> = {
+  bottom: 'to top',
+  top: 'to bottom',
+  left: 'to right',
+  right: 'to left',
+};
+
+/**
+ * Makes the element of a scrim: a full-frame layer behind the rest of the
+ * container (negative z-index), so text is always drawn on top of it
+ */
+export function makeScrimHtml(scrim: Scrim): string {
+  const color = `color-mix(in srgb, ${scrim.color} ${Math.round(scrim.opacity * 100)}%, transparent)`;
+  const background =
+    scrim.type === 'solid'
+      ? color
+      : `linear-gradient(${GRADIENT_DIRECTIONS[scrim.direction]}, ${color} 0%, transparent ${scrim.extent}%)`;
+
+  return `<div class="sts-scrim" style="position: absolute; inset: 0; z-index: -1; background: ${background};"></div>`;
+}
@@ -7,6 +7,7 @@ import {
 
 import {
   AMBIENT,
+  Direction,
   FilterBuffer,
   makeStream,
   makeSilentStream,
@@ -16,7 +17,20 @@ import {
   Stream,
   VisualFilter,
 } from './stream';
-import { Output, SequenceDefinition, FragmentDebugInfo } from './type';
+import {
+  Asset,
+  Fragment,
+  Output,
+  SequenceDefinition,
+  FragmentDebugInfo,
+} from './type';
+
+// Clockwise rotations that make a source upright
+const ROTATION_DIRECTIONS: Record<number, Direction> = {
+  90: Direction.CW,
+  180: Direction.CW2,
+  270: Direction.CCW,
+};
 
 export class Sequence {
   private time: number = 0; // time is absolute
@@ -25,13 +39,19 @@ export class Sequence {
   private audioStream!: Stream;
   private debugInfo: FragmentDebugInfo[] = []; // Collect debug info during build
 
+  // For audio-only outputs video filters go to a buffer that is never rendered,
+  // so the filter graph contains just the audio mix
+  private videoBuf: FilterBuffer;
+
   constructor(
     private buf: FilterBuffer,
     private definition: SequenceDefinition,
     private output: Output,
     private assetManager: AssetManager,
     private expressionContext: ExpressionContext,
-  ) {}
+  ) {
+    this.videoBuf = output.format ? new FilterBuffer() : buf;
+  }
 
   build() {
     let firstOne = true;
@@ -60,156 +80,31 @@ export class Sequence {
         duration: calculatedDuration,
       };
 
-      const asset = this.assetManager.getAssetByName(fragment.assetName);
-      if (!asset) {
-        return;
-      }
-
-      // Create video stream: use actual video if available, otherwise create blank stream
       let currentVideoStream: Stream;
-      if (asset.hasVideo) {
-        currentVideoStream = makeStream(
-          this.assetManager.getVideoInputLabelByAssetName(fragment.assetName),
-          this.buf,
-        );
-      } else {
-        // Create blank transparent video stream for audio-only assets
+      let currentAudioStream: Stream;
+
+      if (fragment.gap || this.isSilentVisual(fragment)) {
+        // Deliberate pause: blank video of the gap color with silence
         currentVideoStream = makeBlankStream(
           calculatedDuration,
           this.output.resolution.width,
           this.output.resolution.height,
           this.output.fps,
-          this.buf,
+          this.videoBuf,
+          fragment.gap?.color,
         );
-      }
-
-      // Create audio stream: use actual audio if available, otherwise create silent stream
-      // If fragment has -sound: off, always use silence
-      let currentAudioStream: Stream;
-      if (fragment.sound === 'off') {
-        // Force silent audio when -sound: off
         currentAudioStream = makeSilentStream(calculatedDuration, this.buf);
-      } else if (asset.hasAudio) {
-        currentAudioStream = makeStream(
-          this.assetManager.getAudioInputLabelByAssetName(fragment.assetName),
-          this.buf,
-        );
       } else {
-        // Create silent audio stream matching the video duration
-        currentAudioStream = makeSilentStream(calculatedDuration, this.buf);
-      }
-
-      // duration and clipping adjustment
-      if (fragment.trimLeft != 0 || calculatedDuration < asset.duration) {
-        // console.log('fragment.trimLeft=' + fragment.trimLeft);
-        // console.log('fragment.duration=' + calculatedDuration);
-        // console.log('asset.duration=' + asset.duration);
-
-        // Only trim video if it came from an actual source
-        if (asset.hasVideo) {
-          currentVideoStream.trim(
-            fragment.trimLeft,
-            fragment.trimLeft + calculatedDuration,
-          );
-        }
-
-        // Only trim audio if it came from an actual source AND sound is not off
-        if (asset.hasAudio && fragment.sound !== 'off') {
-          currentAudioStream.trim(
-            fragment.trimLeft,
-            fragment.trimLeft + calculatedDuration,
-          );
-        }
-      }
-
-      // Convert deprecated JPEG pixel format (yuvj420p) to standard yuv420p early
-      // This prevents swscaler warnings from appearing in all subsequent filters
-      if (asset.hasVideo && asset.type === 'image') {
-        currentVideoStream.convertPixelFormat('yuv420p');
-      }
-
-      // Apply visual filter early for static images (before padding/cloning)
-      // This is more efficient as ffmpeg processes the filter once, then clones the filtered frame
-      if (
-        asset.hasVideo &&
-        asset.type === 'image' &&
-        fragment.visualFilter
-      ) {
-        currentVideoStream.filter(fragment.visualFilter as VisualFilter);
-      }
-
-      if (
-        asset.duration === 0 &&
-        calculatedDuration > 0 &&
-        asset.type === 'image' &&
-        fragment.objectFit !== 'ken-burns'
-      ) {
-        // special case for images - extend static image to desired duration
-        // Skip tpad for Ken Burns - zoompan will generate the frames
-        currentVideoStream.tPad({
-          start: calculatedDuration,
-          startMode: 'clone',
-        });
-      }
-
-      // stream normalization (only for actual video, not synthetic blank video)
-      if (asset.hasVideo) {
-        // fps reduction
-        currentVideoStream.fps(this.output.fps);
-
-        // fitting the video stream into the output frame
-        if (fragment.objectFit === 'ken-burns') {
-          // Ken Burns effect (zoom/pan)
-          currentVideoStream.kenBurns({
-            effect: fragment.objectFitKenBurns,
-            zoom: fragment.objectFitKenBurnsZoom,
-            effectDuration: fragment.objectFitKenBurnsEffectDuration,
-            fragmentDuration: calculatedDuration,
-            easing: fragment.objectFitKenBurnsEasing,
-            width: this.output.resolution.width,
-            height: this.output.resolution.height,
-            fps: this.output.fps,
-            focalX: fragment.objectFitKenBurnsFocalX,
-            focalY: fragment.objectFitKenBurnsFocalY,
-            panStartX: fragment.objectFitKenBurnsPanStartX,
-            panStartY: fragment.objectFitKenBurnsPanStartY,
-            panEndX: fragment.objectFitKenBurnsPanEndX,
-            panEndY: fragment.objectFitKenBurnsPanEndY,
-          });
-        } else if (fragment.objectFit === 'cover') {
-          currentVideoStream.fitOutputCover(this.output.resolution);
-        } else {
-          const options: ObjectFitContainOptions = {};
-          if (fragment.objectFitContain === AMBIENT) {
-            options.ambient = {
-              blurStrength: fragment.objectFitContainAmbientBlurStrength,
-              brightness: fragment.objectFitContainAmbientBrightness,
-              saturation: fragment.objectFitContainAmbientSaturation,
-            };
-          } else if (fragment.objectFitContain === PILLARBOX) {
-            options.pillarbox = {
-              color: fragment.objectFitContainPillarboxColor,
-            };
-          }
-          currentVideoStream.fitOutputContain(this.output.resolution, options);
-        }
-      }
-
-      // adding effects if needed (only for actual video, not synthetic blank video)
-      if (asset.hasVideo) {
-        // chromakey
-        if (fragment.chromakey) {
-          currentVideoStream.chromakey({
-            blend: fragment.chromakeyBlend,
-            similarity: fragment.chromakeySimilarity,
-            color: fragment.chromakeyColor,
-          });
+        const asset = this.assetManager.getAssetByName(fragment.assetName);
+        if (!asset) {
+          return;
         }
 
-        // visual filter (for video assets - images are filtered earlier before padding)
-        if (fragment.visualFilter && asset.type !== 'image') {
-          currentVideoStream.filter(fragment.visualFilter as VisualFilter);
-        }
+        ({ currentVideoStream, currentAudioStream } = this.makeAssetStreams(
+          fragment,
+          asset,
+          calculatedDuration,
+        ));
       }
 
       // transitions
@@ -337,7 +232,7 @@ export class Sequence {
       // Collect debug info
       this.debugInfo.push({
         id: fragment.id,
-        assetName: fragment.assetName,
+        assetName: fragment.gap ? '(gap)' : fragment.assetName,
         startTime: timeContext.start,
         endTime: timeContext.end,
         duration: calculatedDuration,
@@ -352,17 +247,274 @@ export class Sequence {
     });
   }
 
+  /**
+   * Creates the video and audio streams of an asset-backed fragment:
+   * trimming, normalization, fitting into the output frame and effects
+   */
+  private makeAssetStreams(
+    fragment: Fragment,
+    asset: Asset,
+    calculatedDuration: number,
+  ): { currentVideoStream: Stream; currentAudioStream: Stream } {
+    // Create video stream: use actual video if available, otherwise create blank stream
+    let currentVideoStream: Stream;
+    if (asset.hasVideo) {
+      currentVideoStream = makeStream(
+        this.assetManager.getVideoInputLabelByAssetName(fragment.assetName),
+        this.videoBuf,
+      );
+
+      // Inputs are read with -noautorotate: phone footage is turned upright
+      // here, by its rotation metadata or the fragment's -orient override
+      const direction =
+        ROTATION_DIRECTIONS[fragment.orientation ?? asset.rotation];
+      if (direction !== undefined) {
+        currentVideoStream.cwRotate(direction);
+      }
+    } else {
+      // Create blank transparent video stream for audio-only assets
+      currentVideoStream = makeBlankStream(
+        calculatedDuration,
+        this.output.resolution.width,
+        this.output.resolution.height,
+        this.output.fps,
+        this.videoBuf,
+      );
+    }
+
+    // Create audio stream: use actual audio if available, otherwise create silent stream
+    // If fragment has -sound: off, always use silence
+    let currentAudioStream: Stream;
+    if (fragment.sound === 'off') {
+      // Force silent audio when -sound: off
+      currentAudioStream = makeSilentStream(calculatedDuration, this.buf);
+    } else if (asset.hasAudio) {
+      currentAudioStream = makeStream(
+        this.assetManager.getAudioInputLabelByAssetName(fragment.assetName),
+        this.buf,
+      );
+    } else {
+      // Create silent audio stream matching the video duration
+      currentAudioStream = makeSilentStream(calculatedDuration, this.buf);
+    }
+
+    // sub-clips are time ranges of the source input
+    const sourceOffset = asset.subclip ? asset.subclip.start : 0;
+    const trimStart = sourceOffset + fragment.trimLeft;
+
+    // freeze-frame only makes sense for moving pictures
+    const freeze =
+      asset.hasVideo && asset.type === 'video' ? fragment.freeze : undefined;
+
+    if (freeze && freeze.mode === 'at') {
+      // hold a single frame of the source for the whole fragment, no sound
+      const frameTime = sourceOffset + freeze.time;
+      const frameDuration = Math.ceil(1000 / this.output.fps);
+      currentVideoStream
+        .trim(frameTime, frameTime + frameDuration)
+        .tPad({
+          stop: calculatedDuration,
+          stopMode: 'clone',
+        })
+        .trim(0, calculatedDuration);
+      currentAudioStream = makeSilentStream(calculatedDuration, this.buf);
+    } else {
+      // with -freeze: first/last only part of the fragment is actual playback
+      const playDuration = freeze
+        ? Math.max(0, calculatedDuration - freeze.duration)
+        : calculatedDuration;
+
+      // A/V sync correction: the audio window of the source is shifted
+      // against the picture (positive = audio later), see -av-offset
+      const hasSound = asset.hasAudio && fragment.sound !== 'off';
+      const avOffset = hasSound
+        ? (fragment.avOffset ?? asset.avOffset ?? 0)
+        : 0;
+
+      // duration and clipping adjustment
+      if (
+        trimStart != 0 ||
+        playDuration < asset.duration ||
+        freeze ||
+        avOffset !== 0
+      ) {
+        // console.log('fragment.trimLeft=' + fragment.trimLeft);
+        // console.log('fragment.duration=' + calculatedDuration);
+        // console.log('asset.duration=' + asset.duration);
+
+        // never read past the end of a sub-clip into the rest of the source
+        const trimEnd = asset.subclip
+          ? Math.min(trimStart + playDuration, asset.subclip.end)
+          : trimStart + playDuration;
+
+        // Only trim video if it came from an actual source
+        if (asset.hasVideo) {
+          currentVideoStream.trim(trimStart, trimEnd);
+        }
+
+        // Only trim audio if it came from an actual source AND sound is not off
+        if (hasSound && avOffset === 0) {
+          currentAudioStream.trim(trimStart, trimEnd);
+        } else if (hasSound && trimEnd - avOffset <= 0) {
+          // The shift is at least as long as the played window, the sound
+          // starts after the fragment (an empty trim can't be padded)
+          currentAudioStream = makeSilentStream(trimEnd - trimStart, this.buf);
+        } else if (hasSound) {
+          // The shifted window can start before the file (padded with
+          // silence) or run past its end (padded, then cut to length)
+          const audioStart = trimStart - avOffset;
+          const audioEnd = trimEnd - avOffset;
+          currentAudioStream.trim(
+            Math.max(0, audioStart),
+            Math.max(0, audioEnd),
+          );
+          if (audioStart < 0) {
+            currentAudioStream.tPad({ start: -audioStart });
+          }
+          if (avOffset < 0) {
+            currentAudioStream
+              .tPad({ stop: -avOffset })
+              .trim(0, trimEnd - trimStart);
+          }
+        }
+      }
+
+      if (freeze) {
+        // clone the first/last frame, pad the audio with silence
+        const hold = calculatedDuration - playDuration;
+        if (freeze.mode === 'first') {
+          currentVideoStream.tPad({ start: hold, startMode: 'clone' });
+          if (asset.hasAudio && fragment.sound !== 'off') {
+            currentAudioStream.tPad({ start: hold });
+          }
+        } else {
+          currentVideoStream.tPad({ stop: hold, stopMode: 'clone' });
+          if (asset.hasAudio && fragment.sound !== 'off') {
+            currentAudioStream.tPad({ stop: hold });
+          }
+        }
+      }
+    }
+
+    // Convert deprecated JPEG pixel format (yuvj420p) to standard yuv420p early
+    // This prevents swscaler warnings from appearing in all subsequent filters
+    // Sources with alpha (overlays) keep their transparency through the stack
+    if (asset.hasVideo && asset.hasAlpha) {
+      currentVideoStream.convertPixelFormat('yuva420p');
+    } else if (asset.hasVideo && asset.type === 'image') {
+      currentVideoStream.convertPixelFormat('yuv420p');
+    }
+
+    // Apply visual filter early for static images (before padding/cloning)
+    // This is more efficient as ffmpeg processes the filter once, then clones the filtered frame
+    if (
+      asset.hasVideo &&
+      asset.type === 'image' &&
+      fragment.visualFilter
+    ) {
+      currentVideoStream.filter(fragment.visualFilter as VisualFilter);
+    }
+
+    if (
+      asset.duration === 0 &&
+      calculatedDuration > 0 &&
+      asset.type === 'image' &&
+      fragment.objectFit !== 'ken-burns'
+    ) {
+      // special case for images - extend static image to desired duration
+      // Skip tpad for Ken Burns - zoompan will generate the frames
+      currentVideoStream.tPad({
+        start: calculatedDuration,
+        startMode: 'clone',
+      });
+    }
+
+    // stream normalization (only for actual video, not synthetic blank video)
+    if (asset.hasVideo) {
+      // fps reduction
+      currentVideoStream.fps(this.output.fps);
+
+      // fitting the video stream into the output frame
+      if (fragment.objectFit === 'ken-burns') {
+        // Ken Burns effect (zoom/pan)
+        currentVideoStream.kenBurns({
+          effect: fragment.objectFitKenBurns,
+          zoom: fragment.objectFitKenBurnsZoom,
+          zoomBase: fragment.objectFitKenBurnsZoomBase,
+          effectDuration: fragment.objectFitKenBurnsEffectDuration,
+          fragmentDuration: calculatedDuration,
+          easing: fragment.objectFitKenBurnsEasing,
+          width: this.output.resolution.width,
+          height: this.output.resolution.height,
+          fps: this.output.fps,
+          focalX: fragment.objectFitKenBurnsFocalX,
+          focalY: fragment.objectFitKenBurnsFocalY,
+          panStartX: fragment.objectFitKenBurnsPanStartX,
+          panStartY: fragment.objectFitKenBurnsPanStartY,
+          panEndX: fragment.objectFitKenBurnsPanEndX,
+          panEndY: fragment.objectFitKenBurnsPanEndY,
+        });
+      } else if (fragment.objectFit === 'cover') {
+        currentVideoStream.fitOutputCover(this.output.resolution);
+      } else {
+        const options: ObjectFitContainOptions = {};
+        if (fragment.objectFitContain === AMBIENT) {
+          options.ambient = {
+            blurStrength: fragment.objectFitContainAmbientBlurStrength,
+            brightness: fragment.objectFitContainAmbientBrightness,
+            saturation: fragment.objectFitContainAmbientSaturation,
+          };
+        } else if (fragment.objectFitContain === PILLARBOX) {
+          options.pillarbox = {
+            color: fragment.objectFitContainPillarboxColor,
+          };
+        }
+        currentVideoStream.fitOutputContain(this.output.resolution, options);
+      }
+    }
+
+    // adding effects if needed (only for actual video, not synthetic blank video)
+    if (asset.hasVideo) {
+      // chromakey
+      if (fragment.chromakey) {
+        currentVideoStream.chromakey({
+          blend: fragment.chromakeyBlend,
+          similarity: fragment.chromakeySimilarity,
+          color: fragment.chromakeyColor,
+        });
+      }
+
+      // visual filter (for video assets - images are filtered earlier before padding)
+      if (fragment.visualFilter && asset.type !== 'image') {
+        currentVideoStream.filter(fragment.visualFilter as VisualFilter);
+      }
+    }
+
+    return { currentVideoStream, currentAudioStream };
+  }
+
   isEmpty() {
     return !this.definition.fragments.some((fragment) => {
       if (!fragment.enabled) {
         return false;
       }
+      if (fragment.gap || this.isSilentVisual(fragment)) {
+        return true;
+      }
       // Check if fragment has a valid asset
       const asset = this.assetManager.getAssetByName(fragment.assetName);
       return !!asset;
     });
   }
 
+  /**
+   * Containers and apps are not rendered for audio-only outputs,
+   * their fragments only keep the timing (as silence)
+   */
+  private isSilentVisual(fragment: Fragment): boolean {
+    return !!this.output.format && !!(fragment.container || fragment.app);
+  }
+
   overlayWith(sequence: Sequence) {
     this.videoStream.overlayStream(sequence.getVideoStream(), {});
     this.audioStream.overlayStream(sequence.getAudioStream(), {});
@@ -127,13 +127,14 @@ export function makeBlankStream(
   height: number,
   fps: number,
   buf: FilterBuffer,
+  color: string = Colors.Transparent,
 ): Stream {
   const filter = makeColor({
     duration,
     width,
     height,
     fps,
-    color: '#00000000',
+    color,
   });
   buf.append(filter);
   return new Stream(filter.outputs[0], buf);
@@ -316,6 +317,7 @@ export class Stream {
   public kenBurns(parameters: {
     effect: 'zoom-in' | 'zoom-out' | 'pan-left' | 'pan-right' | 'pan-top' | 'pan-bottom';
     zoom: number;
+    zoomBase?: number;
     effectDuration: number;
     fragmentDuration: number;
     easing: 'linear' | 'ease-in' | 'ease-out' | 'ease-in-out';
@@ -333,6 +335,7 @@ export class Stream {
     const kenBurnsRes = makeKenBurns([this.looseEnd], {
       effect: parameters.effect,
       zoom: parameters.zoom,
+      zoomBase: parameters.zoomBase,
       effectDuration: parameters.effectDuration,
       fragmentDuration: parameters.fragmentDuration,
       easing: parameters.easing,
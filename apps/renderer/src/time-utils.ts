@@ -13,3 +13,39 @@ export function formatDuration(ms: number): string {
 
   return `${pad(hours)}:${pad(minutes)}:${pad(seconds)}`;
 }
+
+/**
+ * Formats milliseconds as a YouTube chapter time (M:SS or H:MM:SS)
+ * @param ms - Time in milliseconds
+ * @returns Formatted time string (e.g., "0:00", "12:05" or "1:02:03")
+ */
+export function formatChapterTime(ms: number): string {
+  const time = ms / 1000;
+  const hours = Math.floor(time / 3600);
+  const minutes = Math.floor((time % 3600) / 60);
+  const seconds = Math.floor(time % 60);
+
+  if (hours > 0) {
+    return `${hours}:${minutes.toString().padStart(2, '0')}:${seconds.toString().padStart(2, '0')}`;
+  }
+
+  return `${minutes}:${seconds.toString().padStart(2, '0')}`;
+}
+
+/**
+ * Parses a timecode into milliseconds
+ * Supports "SS.mmm", "MM:SS.mmm" and "HH:MM:SS.mmm" (e.g. "00:03.5" → 3500)
+ * @param value - Timecode string
+ * @returns Time in milliseconds, or undefined if the value is not a timecode
+ */
+export function parseTimecode(value: string): number | undefined {
+  const trimmed = value.trim();
+  if (!/^\d+(?::\d+){0,2}(?:\.\d+)?$/.test(trimmed)) {
+    return undefined;
+  }
+
+  const parts = trimmed.split(':').map((part) => parseFloat(part));
+  const seconds = parts.reduce((total, part) => total * 60 + part, 0);
+
+  return Math.round(seconds * 1000);
+}
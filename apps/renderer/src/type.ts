@@ -19,6 +19,25 @@ export type App = {
   parameters: Record<string, string>; // extra params from data-parameters
 };
 
+export type Gap = {
+  color: string; // fill color of the pause, transparent by default (black in the final render)
+};
+
+// Dim layer drawn under the text of a container, for caption readability
+export type Scrim = {
+  type: 'solid' | 'gradient';
+  color: string; // any CSS color, e.g. "#000", "rgb(20,20,40)"
+  opacity: number; // 0-1, at the darkest point
+  direction: 'top' | 'bottom' | 'left' | 'right'; // edge a gradient is darkest at
+  extent: number; // percent of the frame a gradient fades out over
+};
+
+export type Freeze = {
+  mode: 'first' | 'last' | 'at'; // which frame is held
+  duration: number; // ms the frame is held for (0 = whole fragment, for 'at')
+  time: number; // ms, source time of the held frame ('at' only)
+};
+
 export type AppRenderResult = {
   app: App;
   mode: 'static' | 'animated';
@@ -44,9 +63,18 @@ export type Asset = {
   duration: number; // in ms
   width: number;
   height: number;
-  rotation: number; // rotation in degrees (0, 90, 180, 270)
+  rotation: number; // clockwise rotation in degrees to display the video upright (0, 90, 180, 270)
   hasVideo: boolean; // whether the asset has a video stream
   hasAudio: boolean; // whether the asset has an audio stream
+  variableFrameRate?: boolean; // video with a variable frame rate, conformed to CFR before rendering
+  hasAlpha?: boolean; // video or image with an alpha channel (ProRes 4444, VP9 WebM, PNG, ...)
+  alphaDecoder?: string; // decoder that keeps the alpha channel, e.g. "libvpx-vp9" for VP9 WebM
+  avOffset?: number; // ms, data-av-offset: delay of the audio against the picture, negative plays it earlier
+  subclip?: {
+    from: string; // name of the source asset (e.g. "beach")
+    start: number; // in ms, where the sub-clip starts in the source
+    end: number; // in ms, where the sub-clip ends in the source
+  };
   ai?: {
     integrationName: string; // References AI integration name from <ai> section
     prompt: string; // Generation prompt
@@ -74,6 +102,7 @@ export type Fragment = {
   objectFitContainPillarboxColor: string;
   objectFitKenBurns: 'zoom-in' | 'zoom-out' | 'pan-left' | 'pan-right' | 'pan-top' | 'pan-bottom';
   objectFitKenBurnsZoom: number; // zoom percentage (e.g., 30 = 30% zoom, applies to all effects)
+  objectFitKenBurnsZoomBase?: number; // zoom percentage zoom-in starts from and zoom-out ends at (default 0, from -ken-burns)
   objectFitKenBurnsEffectDuration: number; // duration of the ken burns effect in milliseconds
   objectFitKenBurnsEasing: 'linear' | 'ease-in' | 'ease-out' | 'ease-in-out';
   objectFitKenBurnsFocalX: number; // focal point X in percent (0-100, for zoom effects)
@@ -90,11 +119,38 @@ export type Fragment = {
   sound: 'on' | 'off'; // Whether to use asset audio or replace with silence (default: 'on')
   container?: Container; // Optional container attached to this fragment
   app?: App; // Optional app attached to this fragment
+  freeze?: Freeze; // Optional freeze-frame (from -freeze or -freeze-at)
+  orientation?: number; // clockwise rotation of the source from -orient (0, 90, 180, 270), overrides the asset's rotation metadata
+  avOffset?: number; // ms, -av-offset, overrides the asset's data-av-offset
+  gap?: Gap; // Set when the fragment is a <gap> (deliberate pause, no asset)
   timecodeLabel?: string; // Optional label for timecode (from data-timecode attribute)
 };
 
 export type SequenceDefinition = {
+  id?: string; // optional id attribute of the <sequence> element
   fragments: Fragment[];
+  markers?: Marker[]; // <marker> elements between the fragments
+};
+
+// A named point of a sequence, at the start of the fragment that follows it
+export type Marker = {
+  name: string;
+  fragmentIndex: number; // index of the next fragment, fragments.length at the end
+};
+
+// A resolved marker on the timeline
+export type MarkerTime = {
+  name: string;
+  time: number; // ms
+};
+
+// A font of the <fonts> section, embedded into container pages
+export type Font = {
+  family: string; // font-family name used in the CSS
+  weight: string; // e.g. "400", "700"
+  style: 'normal' | 'italic';
+  path?: string; // absolute path of a local font file (.ttf, .otf, .woff, .woff2)
+  google?: string; // Google Fonts family, downloaded to cache/fonts
 };
 
 export type FragmentDebugInfo = {
@@ -114,6 +170,33 @@ export type SequenceDebugInfo = {
   fragments: FragmentDebugInfo[];
 };
 
+/**
+ * A check of the rendered project, declared with <assert> and evaluated
+ * by "staticstripes test"
+ */
+export type Assertion = {
+  name: string; // id/name attribute, or "assert #<n>"
+  output?: string; // output to check against, the first output if not set
+  at?: number; // in ms, time on the timeline (required by text and pixel checks)
+  sequence?: string; // sequence id or 1-based number, all sequences if not set
+  containsText?: string; // text of a container visible at "at"
+  pixel?: {
+    x: number;
+    y: number;
+    color: { r: number; g: number; b: number };
+    tolerance: number; // max difference per channel, 0-255
+  };
+  duration?: {
+    value: number; // in ms
+    tolerance: number; // in ms
+  };
+};
+
+export type Chapter = {
+  time: number; // in ms, start of the fragment on the timeline
+  label: string; // from the fragment's data-timecode attribute
+};
+
 export type Output = {
   name: string; // e.g. "youtube"
   path: string; // e.g. "./output/video.mp4"
@@ -122,8 +205,18 @@ export type Output = {
     height: number;
   };
   fps: number; // e.g. 30
+  format?: AudioFormat; // audio-only output (no video is rendered)
+  bitrate?: string; // e.g. "8M", video bitrate (audio bitrate for audio-only outputs)
+  alpha?: boolean; // keep transparency: ProRes 4444 (.mov) or VP9 (.webm)
+  baseSize?: {
+    // resolution the containers are designed for, rem scales with the output
+    width: number;
+    height: number;
+  };
 };
 
+export type AudioFormat = 'mp3' | 'aac' | 'flac';
+
 export type FFmpegOption = {
   name: string; // e.g. "preview", "production"
   args: string; // e.g. "-c:v h264_nvenc -preset fast"
@@ -170,6 +263,10 @@ export type AIProvider = {
   model?: string; // e.g. "sonic-v3-5" - optional model name
 };
 
+// strict: unknown elements/properties and missing attributes fail the parse
+// permissive: they are ignored or reported as warnings
+export type ParseMode = 'strict' | 'permissive';
+
 export type ProjectStructure = {
   sequences: SequenceDefinition[];
   assets: Map<string, Asset>;
@@ -0,0 +1,138 @@
+import { existsSync, readFileSync } from 'fs';
+import { dirname, resolve } from 'path';
+import { ParseMode } from './type';
+import { CachePrunePolicy, parseAge, parseByteSize } from './cache';
+
+export const WORKSPACE_CONFIG_FILE_NAME = 'staticstripes.json';
+
+/**
+ * Workspace-wide settings shared by all projects below the config file
+ * Example staticstripes.json:
+ *   { "parseMode": "strict", "libraries": { "brand": "./brand/library.html" } }
+ *   { "cache": { "maxSize": "20G", "maxAge": "30d" } }
+ */
+export type WorkspaceConfig = {
+  path?: string; // absolute path of the config file, if one was found
+  parseMode?: ParseMode;
+  libraries?: Record<string, string>; // asset library namespace => absolute path
+  cache?: CachePrunePolicy; // defaults of "staticstripes cache prune"
+};
+
+/**
+ * Finds the nearest staticstripes.json, starting in the project directory
+ * and walking up to the filesystem root
+ */
+export function findWorkspaceConfigPath(projectDir: string): string | null {
+  let dir = resolve(projectDir);
+
+  while (true) {
+    const candidate = resolve(dir, WORKSPACE_CONFIG_FILE_NAME);
+    if (existsSync(candidate)) {
+      return candidate;
+    }
+
+    const parent = dirname(dir);
+    if (parent === dir) {
+      return null;
+    }
+    dir = parent;
+  }
+}
+
+/**
+ * Loads the workspace config of a project, empty config if there is none
+ * @throws Error if the config file is not valid JSON or has invalid values
+ */
+export function loadWorkspaceConfig(projectDir: string): WorkspaceConfig {
+  const path = findWorkspaceConfigPath(projectDir);
+  if (!path) {
+    return {};
+  }
+
+  let raw: any;
+  try {
+    raw = JSON.parse(readFileSync(path, 'utf-8'));
+  } catch (error) {
+    throw new Error(
+      `Invalid workspace config ${path}: ${error instanceof Error ? error.message : String(error)}`,
+    );
+  }
+
+  const config: WorkspaceConfig = { path };
+
+  if (raw.parseMode !== undefined) {
+    if (raw.parseMode !== 'strict' && raw.parseMode !== 'permissive') {
+      throw new Error(
+        `Invalid workspace config ${path}: parseMode must be "strict" or "permissive"`,
+      );
+    }
+    config.parseMode = raw.parseMode;
+  }
+
+  if (raw.libraries !== undefined) {
+    if (
+      typeof raw.libraries !== 'object' ||
+      raw.libraries === null ||
+      Array.isArray(raw.libraries) ||
+      Object.values(raw.libraries).some((value) => typeof value !== 'string')
+    ) {
+      throw new Error(
+        `Invalid workspace config ${path}: libraries must map namespaces to library file paths`,
+      );
+    }
+
+    // Library paths are relative to the config file
+    config.libraries = Object.fromEntries(
+      Object.entries(raw.libraries as Record<string, string>).map(
+        ([namespace, libraryPath]) => [
+          namespace,
+          resolve(dirname(path), libraryPath),
+        ],
+      ),
+    );
+  }
+
+  if (raw.cache !== undefined) {
+    if (typeof raw.cache !== 'object' || raw.cache === null) {
+      throw new Error(
+        `Invalid workspace config ${path}: cache must be an object`,
+      );
+    }
+
+    const cache: CachePrunePolicy = {};
+    if (raw.cache.maxSize !== undefined) {
+      cache.maxSize = parseByteSize(String(raw.cache.maxSize));
+      if (cache.maxSize === undefined) {
+        throw new Error(
+          `Invalid workspace config ${path}: cache.maxSize must be a size like "20G"`,
+        );
+      }
+    }
+    if (raw.cache.maxAge !== undefined) {
+      cache.maxAge = parseAge(String(raw.cache.maxAge));
+      if (cache.maxAge === undefined) {
+        throw new Error(
+          `Invalid workspace config ${path}: cache.maxAge must be an age like "30d"`,
+        );
+      }
+    }
+    config.cache = cache;
+  }
+
+  return config;
+}
+
+/**
+ * Resolves the parse mode: --strict/--no-strict flag, then the workspace
+ * config, then permissive
+ */
+export function resolveParseMode(
+  strictFlag: boolean | undefined,
+  config: WorkspaceConfig,
+): ParseMode {
+  if (strictFlag !== undefined) {
+    return strictFlag ? 'strict' : 'permissive';
+  }
+
+  return config.parseMode ?? 'permissive';
+}